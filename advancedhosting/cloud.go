@@ -20,11 +20,14 @@ import (
 	"context"
 	"fmt"
 	"io"
-	"os"
+	"net/http"
+	"time"
 
 	"github.com/advancedhosting/advancedhosting-api-go/ah"
+	"golang.org/x/oauth2"
 
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
 	cloudprovider "k8s.io/cloud-provider"
 	"k8s.io/klog"
 )
@@ -32,40 +35,63 @@ import (
 const (
 	providerName            = "advancedhosting"
 	ahAPIToken              = "AH_API_TOKEN"
+	ahAPITokenFile          = "AH_API_TOKEN_FILE"
 	ahAPIBaseURL            = "AH_API_URL"
 	ahClusterPrivateNetwork = "AH_CLUSTER_PRIVATE_NETWORK_NUMBER"
 	ahClusterDatacenter     = "AH_CLUSTER_DATACENTER"
+	ahClusterID             = "AH_CLUSTER_ID"
 )
 
 type cloud struct {
 	client        *ah.APIClient
 	instances     cloudprovider.Instances
+	instancesV2   cloudprovider.InstancesV2
 	zones         cloudprovider.Zones
 	loadbalancers cloudprovider.LoadBalancer
 	clusterInfo   *clusterInfo
+	tokenSource   *fileTokenSource
+	lbGC          *loadBalancerGC
 }
 
 type clusterInfo struct {
 	PrivateNetworkID string
 	DatacenterID     string
+	DatacenterSlug   string
+	ClusterID        string
+	LoadBalancer     loadBalancerConfig
+	Polling          pollingConfig
 	kclient          kubernetes.Interface
+	recorder         record.EventRecorder
 }
 
-func newCloud() (cloudprovider.Interface, error) {
+func newCloud(config io.Reader) (cloudprovider.Interface, error) {
 
-	token := os.Getenv(ahAPIToken)
-	if token == "" {
-		return nil, fmt.Errorf("AdvancedHosting API token is required")
+	cfg, err := readConfig(config)
+	if err != nil {
+		return nil, err
 	}
 
-	baseURL := os.Getenv(ahAPIBaseURL)
-	if baseURL == "" {
-		baseURL = "https://api.websa.com"
+	var tokenSource oauth2.TokenSource = oauth2.StaticTokenSource(&oauth2.Token{AccessToken: cfg.Token})
+	var fileToken *fileTokenSource
+	if cfg.TokenFile != "" {
+		fileToken, err = newFileTokenSource(cfg.TokenFile)
+		if err != nil {
+			return nil, err
+		}
+		tokenSource = fileToken
+	}
+
+	token, err := tokenSource.Token()
+	if err != nil {
+		return nil, err
 	}
 
+	httpClient := newHTTPClient(tokenSource, cfg.Timeouts.API.Duration)
+
 	clientOptions := &ah.ClientOptions{
-		Token:   token,
-		BaseURL: baseURL,
+		Token:      token.AccessToken,
+		BaseURL:    cfg.APIURL,
+		HTTPClient: httpClient,
 	}
 
 	client, err := ah.NewAPIClient(clientOptions)
@@ -73,39 +99,60 @@ func newCloud() (cloudprovider.Interface, error) {
 		return nil, fmt.Errorf("an error occurred while creating Api Client: %s", err)
 	}
 
-	clusterInfo, err := newClusterInfo(client)
+	clusterInfo, err := newClusterInfo(client, cfg)
 	if err != nil {
 		return nil, fmt.Errorf("an error occurred while creating clusterInfo: %s", err)
 	}
 
+	instances := newInstances(client)
+	loadbalancers := newLoadbalancers(client, clusterInfo)
+
+	var lbGC *loadBalancerGC
+	if cfg.GarbageCollector.Enabled {
+		lbGC = newLoadBalancerGC(loadbalancers, cfg.GarbageCollector)
+	}
+
 	return &cloud{
 		client:        client,
 		clusterInfo:   clusterInfo,
-		instances:     newInstances(client),
-		loadbalancers: newLoadbalancers(client, clusterInfo),
+		instances:     instances,
+		instancesV2:   instances,
+		zones:         newZones(instances, clusterInfo),
+		loadbalancers: loadbalancers,
+		tokenSource:   fileToken,
+		lbGC:          lbGC,
 	}, nil
 }
 
-func newClusterInfo(client *ah.APIClient) (*clusterInfo, error) {
-	pnNumber := os.Getenv(ahClusterPrivateNetwork)
-	if pnNumber == "" {
-		return nil, fmt.Errorf("private Network Number is required")
+// newHTTPClient returns an HTTP client authorizing every request with the current token of the source.
+// oauth2.NewClient is not used, as its ReuseTokenSource keeps a token without expiry forever
+// and a reloaded token would never be sent.
+func newHTTPClient(tokenSource oauth2.TokenSource, timeout time.Duration) *http.Client {
+	return &http.Client{
+		Transport: &oauth2.Transport{Source: tokenSource},
+		Timeout:   timeout,
 	}
-	pnID, err := privateNetworkIDbyNumber(pnNumber, client)
+}
+
+func newClusterInfo(client *ah.APIClient, cfg *cloudConfig) (*clusterInfo, error) {
+	pnID, err := privateNetworkIDbyNumber(cfg.PrivateNetworkNumber, client)
 	if err != nil {
 		return nil, fmt.Errorf("error getting pnID: %v", err)
 	}
-	datacenterSlug := os.Getenv(ahClusterDatacenter)
-	if datacenterSlug == "" {
-		return nil, fmt.Errorf("datacenter ID is required")
-	}
 
-	datacenterID, err := datacenterIDBySlug(datacenterSlug, client)
+	datacenterID, err := datacenterIDBySlug(cfg.Datacenter, client)
 	if err != nil {
 		return nil, fmt.Errorf("error getting datacenterID: %v", err)
 	}
 
-	return &clusterInfo{PrivateNetworkID: pnID, DatacenterID: datacenterID}, nil
+	return &clusterInfo{
+		PrivateNetworkID: pnID,
+		DatacenterID:     datacenterID,
+		DatacenterSlug:   cfg.Datacenter,
+		ClusterID:        cfg.ClusterID,
+		LoadBalancer:     cfg.LoadBalancer,
+		Polling:          cfg.Polling,
+	}, nil
 }
 
 func privateNetworkIDbyNumber(pnNumber string, client *ah.APIClient) (string, error) {
@@ -137,17 +184,26 @@ func datacenterIDBySlug(datacenterSlug string, client *ah.APIClient) (string, er
 }
 
 func init() {
-	cloudprovider.RegisterCloudProvider(providerName, func(io.Reader) (cloudprovider.Interface, error) {
-		return newCloud()
+	cloudprovider.RegisterCloudProvider(providerName, func(config io.Reader) (cloudprovider.Interface, error) {
+		return newCloud(config)
 	})
 }
 
 func (c *cloud) Initialize(clientBuilder cloudprovider.ControllerClientBuilder, stop <-chan struct{}) {
 
 	c.clusterInfo.kclient = clientBuilder.ClientOrDie("advancedhosting-cloud-controller-manager")
+	c.clusterInfo.recorder = newEventRecorder(c.clusterInfo.kclient)
 
 	klog.Infof("clientset initialized")
 
+	if c.tokenSource != nil {
+		go c.tokenSource.Run(stop)
+	}
+
+	if c.lbGC != nil {
+		go c.lbGC.Run(stop)
+	}
+
 }
 
 func (c *cloud) LoadBalancer() (cloudprovider.LoadBalancer, bool) {
@@ -159,11 +215,11 @@ func (c *cloud) Instances() (cloudprovider.Instances, bool) {
 }
 
 func (c *cloud) InstancesV2() (cloudprovider.InstancesV2, bool) {
-	return nil, false
+	return c.instancesV2, true
 }
 
 func (c *cloud) Zones() (cloudprovider.Zones, bool) {
-	return c.zones, false
+	return c.zones, true
 }
 
 func (c *cloud) Clusters() (cloudprovider.Clusters, bool) {
@@ -179,5 +235,5 @@ func (c *cloud) ProviderName() string {
 }
 
 func (c *cloud) HasClusterID() bool {
-	return false
+	return c.clusterInfo.ClusterID != ""
 }
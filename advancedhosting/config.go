@@ -0,0 +1,239 @@
+/*
+Copyright 2021 Advanced Hosting
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ah
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	cloudConfigVersion = "v1"
+
+	defaultAPIBaseURL         = "https://api.websa.com"
+	defaultBalancingAlgorithm = "round_robin"
+	defaultAPITimeout         = 30 * time.Second
+	defaultGCInterval         = 10 * time.Minute
+	defaultGCGracePeriod      = time.Hour
+
+	defaultPollingInitialInterval = 2 * time.Second
+	defaultPollingMaxInterval     = time.Minute
+	defaultPollingFactor          = 2.0
+	defaultPollingJitter          = 0.1
+	defaultPollingTimeout         = 15 * time.Minute
+)
+
+// cloudConfig is the content of the file passed with --cloud-config.
+//
+// Example:
+//
+//	version: v1
+//	tokenFile: /etc/advancedhosting/token
+//	privateNetworkNumber: NET14520581
+//	datacenter: ams1
+//	clusterID: production
+//	loadBalancer:
+//	  balancingAlgorithm: round_robin
+//	timeouts:
+//	  api: 30s
+//	garbageCollector:
+//	  enabled: true
+//	  dryRun: true
+//	  interval: 10m
+//	  gracePeriod: 1h
+//	polling:
+//	  initialInterval: 2s
+//	  maxInterval: 1m
+//	  factor: 2
+//	  jitter: 0.1
+//	  timeout: 15m
+type cloudConfig struct {
+	Version              string             `json:"version"`
+	Token                string             `json:"token,omitempty"`
+	TokenFile            string             `json:"tokenFile,omitempty"`
+	APIURL               string             `json:"apiURL,omitempty"`
+	PrivateNetworkNumber string             `json:"privateNetworkNumber,omitempty"`
+	Datacenter           string             `json:"datacenter,omitempty"`
+	ClusterID            string             `json:"clusterID,omitempty"`
+	LoadBalancer         loadBalancerConfig `json:"loadBalancer,omitempty"`
+	Timeouts             timeoutsConfig     `json:"timeouts,omitempty"`
+	GarbageCollector     gcConfig           `json:"garbageCollector,omitempty"`
+	Polling              pollingConfig      `json:"polling,omitempty"`
+}
+
+// loadBalancerConfig holds the defaults applied to load balancers
+// when the service has no matching annotation.
+type loadBalancerConfig struct {
+	BalancingAlgorithm string `json:"balancingAlgorithm,omitempty"`
+}
+
+type timeoutsConfig struct {
+	API metav1.Duration `json:"api,omitempty"`
+}
+
+// gcConfig configures the garbage collector of orphaned load balancers.
+type gcConfig struct {
+	Enabled     bool            `json:"enabled,omitempty"`
+	DryRun      bool            `json:"dryRun,omitempty"`
+	Interval    metav1.Duration `json:"interval,omitempty"`
+	GracePeriod metav1.Duration `json:"gracePeriod,omitempty"`
+}
+
+// pollingConfig configures how often the state of submitted load balancer
+// changes is checked and how long they may take to be applied.
+// Factor and Jitter are pointers, so that an explicit 0 is not taken for an unset value.
+type pollingConfig struct {
+	InitialInterval metav1.Duration `json:"initialInterval,omitempty"`
+	MaxInterval     metav1.Duration `json:"maxInterval,omitempty"`
+	Factor          *float64        `json:"factor,omitempty"`
+	Jitter          *float64        `json:"jitter,omitempty"`
+	Timeout         metav1.Duration `json:"timeout,omitempty"`
+}
+
+// readConfig parses the cloud config file (if any) and applies
+// environment variable overrides and defaults on top of it.
+func readConfig(r io.Reader) (*cloudConfig, error) {
+	cfg := &cloudConfig{Version: cloudConfigVersion}
+
+	if r != nil {
+		data, err := ioutil.ReadAll(r)
+		if err != nil {
+			return nil, fmt.Errorf("error reading cloud config: %v", err)
+		}
+		if err := yaml.UnmarshalStrict(data, cfg); err != nil {
+			return nil, fmt.Errorf("error parsing cloud config: %v", err)
+		}
+		if cfg.Version != cloudConfigVersion {
+			return nil, fmt.Errorf("unsupported cloud config version %q, expected %q", cfg.Version, cloudConfigVersion)
+		}
+	}
+
+	cfg.applyEnv()
+	cfg.applyDefaults()
+
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+func (cfg *cloudConfig) applyEnv() {
+	overrides := map[string]*string{
+		ahAPIToken:              &cfg.Token,
+		ahAPITokenFile:          &cfg.TokenFile,
+		ahAPIBaseURL:            &cfg.APIURL,
+		ahClusterPrivateNetwork: &cfg.PrivateNetworkNumber,
+		ahClusterDatacenter:     &cfg.Datacenter,
+		ahClusterID:             &cfg.ClusterID,
+	}
+	for env, field := range overrides {
+		if v := os.Getenv(env); v != "" {
+			*field = v
+		}
+	}
+
+	// A token passed through the environment wins over a token file from the config.
+	if os.Getenv(ahAPIToken) != "" && os.Getenv(ahAPITokenFile) == "" {
+		cfg.TokenFile = ""
+	}
+}
+
+func (cfg *cloudConfig) applyDefaults() {
+	if cfg.APIURL == "" {
+		cfg.APIURL = defaultAPIBaseURL
+	}
+	if cfg.LoadBalancer.BalancingAlgorithm == "" {
+		cfg.LoadBalancer.BalancingAlgorithm = defaultBalancingAlgorithm
+	}
+	if cfg.Timeouts.API.Duration == 0 {
+		cfg.Timeouts.API.Duration = defaultAPITimeout
+	}
+	if cfg.GarbageCollector.Interval.Duration == 0 {
+		cfg.GarbageCollector.Interval.Duration = defaultGCInterval
+	}
+	if cfg.GarbageCollector.GracePeriod.Duration == 0 {
+		cfg.GarbageCollector.GracePeriod.Duration = defaultGCGracePeriod
+	}
+	cfg.Polling.applyDefaults()
+}
+
+func (p *pollingConfig) applyDefaults() {
+	if p.InitialInterval.Duration == 0 {
+		p.InitialInterval.Duration = defaultPollingInitialInterval
+	}
+	if p.MaxInterval.Duration == 0 {
+		p.MaxInterval.Duration = defaultPollingMaxInterval
+	}
+	if p.Factor == nil {
+		p.Factor = float64Ptr(defaultPollingFactor)
+	}
+	if p.Jitter == nil {
+		p.Jitter = float64Ptr(defaultPollingJitter)
+	}
+	if p.Timeout.Duration == 0 {
+		p.Timeout.Duration = defaultPollingTimeout
+	}
+}
+
+func (cfg *cloudConfig) validate() error {
+	if cfg.Token == "" && cfg.TokenFile == "" {
+		return fmt.Errorf("AdvancedHosting API token is required")
+	}
+	if cfg.PrivateNetworkNumber == "" {
+		return fmt.Errorf("private Network Number is required")
+	}
+	if cfg.Datacenter == "" {
+		return fmt.Errorf("datacenter ID is required")
+	}
+	if cfg.ClusterID != "" {
+		if errs := validation.IsDNS1123Label(cfg.ClusterID); len(errs) > 0 {
+			return fmt.Errorf("invalid cluster ID %q: %s", cfg.ClusterID, strings.Join(errs, ", "))
+		}
+		if strings.Contains(cfg.ClusterID, clusterIDSeparator) {
+			return fmt.Errorf("invalid cluster ID %q: must not contain %q", cfg.ClusterID, clusterIDSeparator)
+		}
+	}
+	if cfg.GarbageCollector.Enabled && cfg.ClusterID == "" {
+		return fmt.Errorf("cluster ID is required to enable the load balancer garbage collector")
+	}
+	if !balancingAlgorithms[cfg.LoadBalancer.BalancingAlgorithm] {
+		return fmt.Errorf("unsupported load balancer balancing algorithm %q", cfg.LoadBalancer.BalancingAlgorithm)
+	}
+	if cfg.Polling.MaxInterval.Duration < cfg.Polling.InitialInterval.Duration {
+		return fmt.Errorf("polling max interval must not be less than the initial interval")
+	}
+	if *cfg.Polling.Factor < 1 {
+		return fmt.Errorf("polling factor must not be less than 1")
+	}
+	if *cfg.Polling.Jitter < 0 {
+		return fmt.Errorf("polling jitter must not be negative")
+	}
+	return nil
+}
+
+func float64Ptr(v float64) *float64 {
+	return &v
+}
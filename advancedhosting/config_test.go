@@ -0,0 +1,211 @@
+/*
+Copyright 2021 Advanced Hosting
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ah
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const testCloudConfig = `
+version: v1
+token: config-token
+apiURL: https://api.example.com
+privateNetworkNumber: NET1
+datacenter: ams1
+clusterID: test-cluster
+loadBalancer:
+  balancingAlgorithm: least_requests
+timeouts:
+  api: 10s
+`
+
+func unsetConfigEnv() {
+	for _, env := range []string{ahAPIToken, ahAPITokenFile, ahAPIBaseURL, ahClusterPrivateNetwork, ahClusterDatacenter, ahClusterID} {
+		os.Unsetenv(env)
+	}
+}
+
+func TestConfig_ReadConfig(t *testing.T) {
+	unsetConfigEnv()
+
+	cfg, err := readConfig(strings.NewReader(testCloudConfig))
+
+	expectedResult := &cloudConfig{
+		Version:              "v1",
+		Token:                "config-token",
+		APIURL:               "https://api.example.com",
+		PrivateNetworkNumber: "NET1",
+		Datacenter:           "ams1",
+		ClusterID:            "test-cluster",
+		LoadBalancer:         loadBalancerConfig{BalancingAlgorithm: "least_requests"},
+		Timeouts:             timeoutsConfig{API: metav1.Duration{Duration: 10 * time.Second}},
+		GarbageCollector: gcConfig{
+			Interval:    metav1.Duration{Duration: defaultGCInterval},
+			GracePeriod: metav1.Duration{Duration: defaultGCGracePeriod},
+		},
+		Polling: pollingConfig{
+			InitialInterval: metav1.Duration{Duration: defaultPollingInitialInterval},
+			MaxInterval:     metav1.Duration{Duration: defaultPollingMaxInterval},
+			Factor:          float64Ptr(defaultPollingFactor),
+			Jitter:          float64Ptr(defaultPollingJitter),
+			Timeout:         metav1.Duration{Duration: defaultPollingTimeout},
+		},
+	}
+
+	if err != nil {
+		t.Errorf("Unexpected Error: %v", err)
+	}
+
+	if !reflect.DeepEqual(expectedResult, cfg) {
+		t.Errorf("Unexpected result, expected %v. got: %v", expectedResult, cfg)
+	}
+
+}
+
+func TestConfig_ReadConfigEnvOverrides(t *testing.T) {
+	unsetConfigEnv()
+	defer unsetConfigEnv()
+
+	os.Setenv(ahAPIToken, "env-token")
+	os.Setenv(ahClusterDatacenter, "ams2")
+
+	cfg, err := readConfig(strings.NewReader(testCloudConfig))
+
+	if err != nil {
+		t.Fatalf("Unexpected Error: %v", err)
+	}
+
+	if cfg.Token != "env-token" {
+		t.Errorf("Unexpected result, expected %v. got: %v", "env-token", cfg.Token)
+	}
+
+	if cfg.Datacenter != "ams2" {
+		t.Errorf("Unexpected result, expected %v. got: %v", "ams2", cfg.Datacenter)
+	}
+
+	if cfg.PrivateNetworkNumber != "NET1" {
+		t.Errorf("Unexpected result, expected %v. got: %v", "NET1", cfg.PrivateNetworkNumber)
+	}
+
+}
+
+func TestConfig_ReadConfigFromEnvOnly(t *testing.T) {
+	unsetConfigEnv()
+	defer unsetConfigEnv()
+
+	os.Setenv(ahAPIToken, "env-token")
+	os.Setenv(ahClusterPrivateNetwork, "NET1")
+	os.Setenv(ahClusterDatacenter, "ams1")
+
+	cfg, err := readConfig(nil)
+
+	if err != nil {
+		t.Fatalf("Unexpected Error: %v", err)
+	}
+
+	if cfg.APIURL != defaultAPIBaseURL {
+		t.Errorf("Unexpected result, expected %v. got: %v", defaultAPIBaseURL, cfg.APIURL)
+	}
+
+	if cfg.LoadBalancer.BalancingAlgorithm != defaultBalancingAlgorithm {
+		t.Errorf("Unexpected result, expected %v. got: %v", defaultBalancingAlgorithm, cfg.LoadBalancer.BalancingAlgorithm)
+	}
+
+}
+
+func TestConfig_ReadConfigUnsupportedVersion(t *testing.T) {
+	unsetConfigEnv()
+
+	_, err := readConfig(strings.NewReader("version: v2\ntoken: test\n"))
+
+	if err == nil || !strings.Contains(err.Error(), "unsupported cloud config version") {
+		t.Errorf("Unexpected Error: %v", err)
+	}
+
+}
+
+func TestConfig_ReadConfigInvalidClusterID(t *testing.T) {
+	unsetConfigEnv()
+	defer unsetConfigEnv()
+
+	os.Setenv(ahClusterID, "prod--eu")
+
+	_, err := readConfig(strings.NewReader(testCloudConfig))
+
+	if err == nil || !strings.Contains(err.Error(), "invalid cluster ID") {
+		t.Errorf("Unexpected Error: %v", err)
+	}
+
+}
+
+func TestConfig_ReadConfigInvalidBalancingAlgorithm(t *testing.T) {
+	unsetConfigEnv()
+
+	_, err := readConfig(strings.NewReader(strings.Replace(testCloudConfig, "least_requests", "sticky", 1)))
+
+	if err == nil || err.Error() != `unsupported load balancer balancing algorithm "sticky"` {
+		t.Errorf("Unexpected Error: %v", err)
+	}
+
+}
+
+func TestConfig_ReadConfigInvalidPolling(t *testing.T) {
+	unsetConfigEnv()
+
+	_, err := readConfig(strings.NewReader(testCloudConfig + "polling:\n  initialInterval: 1m\n  maxInterval: 10s\n"))
+
+	if err == nil || !strings.Contains(err.Error(), "polling max interval") {
+		t.Errorf("Unexpected Error: %v", err)
+	}
+
+}
+
+func TestConfig_ReadConfigPollingWithoutJitter(t *testing.T) {
+	unsetConfigEnv()
+
+	cfg, err := readConfig(strings.NewReader(testCloudConfig + "polling:\n  jitter: 0\n"))
+
+	if err != nil {
+		t.Fatalf("Unexpected Error: %v", err)
+	}
+
+	if *cfg.Polling.Jitter != 0 {
+		t.Errorf("Unexpected result, expected %v. got: %v", 0, *cfg.Polling.Jitter)
+	}
+
+	if *cfg.Polling.Factor != defaultPollingFactor {
+		t.Errorf("Unexpected result, expected %v. got: %v", defaultPollingFactor, *cfg.Polling.Factor)
+	}
+
+}
+
+func TestConfig_ReadConfigPollingZeroFactor(t *testing.T) {
+	unsetConfigEnv()
+
+	_, err := readConfig(strings.NewReader(testCloudConfig + "polling:\n  factor: 0\n"))
+
+	if err == nil || !strings.Contains(err.Error(), "polling factor") {
+		t.Errorf("Unexpected Error: %v", err)
+	}
+
+}
@@ -0,0 +1,70 @@
+/*
+Copyright 2021 Advanced Hosting
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ah
+
+import (
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+const eventComponent = "advancedhosting-cloud-controller-manager"
+
+// Reasons of the events recorded on services.
+const (
+	eventReasonCreatingLoadBalancer       = "CreatingLoadBalancer"
+	eventReasonAdoptingLoadBalancer       = "AdoptingLoadBalancer"
+	eventReasonUpdatingLoadBalancer       = "UpdatingLoadBalancer"
+	eventReasonDeletingLoadBalancer       = "DeletingLoadBalancer"
+	eventReasonDeletedLoadBalancer        = "DeletedLoadBalancer"
+	eventReasonLoadBalancerNotOwned       = "LoadBalancerNotOwned"
+	eventReasonAssigningIPAddress         = "AssigningIPAddress"
+	eventReasonReleasingIPAddress         = "ReleasingIPAddress"
+	eventReasonRetainingIPAddress         = "RetainingIPAddress"
+	eventReasonDeletingIPAddress          = "DeletingIPAddress"
+	eventReasonReservedIPAddressFailed    = "ReservedIPAddressFailed"
+	eventReasonNoPublicIPAddress          = "NoPublicIPAddress"
+	eventReasonCreatingForwardingRule     = "CreatingForwardingRule"
+	eventReasonDeletingForwardingRule     = "DeletingForwardingRule"
+	eventReasonCreatingHealthCheck        = "CreatingHealthCheck"
+	eventReasonUpdatingHealthCheck        = "UpdatingHealthCheck"
+	eventReasonDeletingHealthCheck        = "DeletingHealthCheck"
+	eventReasonAddingBackendNodes         = "AddingBackendNodes"
+	eventReasonRemovingBackendNode        = "RemovingBackendNode"
+	eventReasonNoBackendNodes             = "NoBackendNodes"
+	eventReasonSkippedBackendNode         = "SkippedBackendNode"
+	eventReasonInvalidAnnotations         = "InvalidAnnotations"
+	eventReasonUnsupportedConfiguration   = "UnsupportedConfiguration"
+	eventReasonPendingOperationTimeout    = "PendingOperationTimeout"
+	eventReasonSessionAffinityNotEnforced = "SessionAffinityNotEnforced"
+)
+
+func newEventRecorder(kclient kubernetes.Interface) record.EventRecorder {
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: kclient.CoreV1().Events("")})
+	return broadcaster.NewRecorder(scheme.Scheme, v1.EventSource{Component: eventComponent})
+}
+
+// eventf records an event on the service. Events are dropped until the recorder is set in Initialize.
+func (l *loadbalancers) eventf(service *v1.Service, eventType, reason, messageFmt string, args ...interface{}) {
+	if l.clusterInfo.recorder == nil {
+		return
+	}
+	l.clusterInfo.recorder.Eventf(service, eventType, reason, messageFmt, args...)
+}
@@ -31,7 +31,17 @@ import (
 
 const ahProviderPrefix = "advancedhosting://"
 
-var providerIDRegexp = regexp.MustCompile(fmt.Sprintf("%s(?P<instanceID>.*)", ahProviderPrefix))
+var providerIDRegexp = regexp.MustCompile(fmt.Sprintf("^%s(?P<instanceID>.+)$", ahProviderPrefix))
+
+// ambiguousInstanceNameError is returned when several instances have the name of a node.
+type ambiguousInstanceNameError struct {
+	name  types.NodeName
+	count int
+}
+
+func (e *ambiguousInstanceNameError) Error() string {
+	return fmt.Sprintf("%d instances are named %q", e.count, e.name)
+}
 
 type instances struct {
 	client *ah.APIClient
@@ -128,6 +138,56 @@ func (i *instances) InstanceShutdownByProviderID(ctx context.Context, providerID
 	return instance.State == ah.InstanceShutDownStatus, nil
 }
 
+// InstanceExists returns true if the instance for the given node exists according to the cloud provider.
+// Use the node.name or node.spec.providerID field to find the node in the cloud provider.
+func (i *instances) InstanceExists(ctx context.Context, node *v1.Node) (bool, error) {
+	if _, err := i.instanceByNode(ctx, node); err != nil {
+		if err == ah.ErrResourceNotFound || err == cloudprovider.InstanceNotFound {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// InstanceShutdown returns true if the instance is shutdown according to the cloud provider.
+// Use the node.name or node.spec.providerID field to find the node in the cloud provider.
+func (i *instances) InstanceShutdown(ctx context.Context, node *v1.Node) (bool, error) {
+	instance, err := i.instanceByNode(ctx, node)
+	if err != nil {
+		return false, err
+	}
+	return instance.State == ah.InstanceShutDownStatus, nil
+}
+
+// InstanceMetadata returns the instance's metadata. The values returned in InstanceMetadata are
+// translated into specific fields in the Node object on registration.
+// Use the node.name or node.spec.providerID field to find the node in the cloud provider.
+func (i *instances) InstanceMetadata(ctx context.Context, node *v1.Node) (*cloudprovider.InstanceMetadata, error) {
+	instance, err := i.instanceByNode(ctx, node)
+	if err != nil {
+		return nil, err
+	}
+
+	addresses, err := i.instanceAddresses(instance)
+	if err != nil {
+		return nil, err
+	}
+
+	return &cloudprovider.InstanceMetadata{
+		ProviderID:    ahProviderPrefix + instance.ID,
+		InstanceType:  instance.Image.Slug,
+		NodeAddresses: addresses,
+	}, nil
+}
+
+func (i *instances) instanceByNode(ctx context.Context, node *v1.Node) (*ah.Instance, error) {
+	if node.Spec.ProviderID == "" {
+		return i.instanceByName(ctx, types.NodeName(node.Name))
+	}
+	return i.instanceByProviderID(ctx, node.Spec.ProviderID)
+}
+
 func (i *instances) instanceByName(ctx context.Context, nodeName types.NodeName) (*ah.Instance, error) {
 	options := &ah.ListOptions{
 		Filters: []ah.FilterInterface{
@@ -143,10 +203,16 @@ func (i *instances) instanceByName(ctx context.Context, nodeName types.NodeName)
 		return nil, err
 	}
 
-	if len(instances) != 1 {
+	if len(instances) == 0 {
 		return nil, cloudprovider.InstanceNotFound
 	}
 
+	// An ambiguous name must not be reported as a missing instance: the
+	// node lifecycle controller deletes nodes whose instance does not exist.
+	if len(instances) > 1 {
+		return nil, &ambiguousInstanceNameError{name: nodeName, count: len(instances)}
+	}
+
 	return &instances[0], nil
 }
 
@@ -25,7 +25,9 @@ import (
 	"github.com/advancedhosting/advancedhosting-cloud-controller-manager/advancedhosting/mocks"
 	"github.com/golang/mock/gomock"
 	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	cloudprovider "k8s.io/cloud-provider"
 )
 
 func testInstanceGetResponse() *ah.Instance {
@@ -51,6 +53,9 @@ func testInstanceGetResponse() *ah.Instance {
 				Slug: "test-slug",
 			},
 		},
+		Datacenter: &ah.Datacenter{
+			Slug: "ams1",
+		},
 	}
 }
 
@@ -343,3 +348,158 @@ func TestInstances_InstanceShutdownByProviderID_InstanceInRunningState(t *testin
 	}
 
 }
+
+func TestInstances_InstanceExists(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	defer ctrl.Finish()
+
+	mockedInstancesAPI := mocks.NewMockInstancesAPI(ctrl)
+
+	mockedInstancesAPI.EXPECT().Get(gomock.Any(), gomock.Eq("test-worker-id")).Return(testInstanceGetResponse(), nil)
+
+	mockedClient := &ah.APIClient{Instances: mockedInstancesAPI}
+	instances := newInstances(mockedClient)
+
+	node := &v1.Node{Spec: v1.NodeSpec{ProviderID: "advancedhosting://test-worker-id"}}
+
+	isExist, err := instances.InstanceExists(context.TODO(), node)
+
+	if err != nil {
+		t.Errorf("Unexpected Error: %v", err)
+	}
+
+	if !isExist {
+		t.Errorf("Unexpected result")
+	}
+
+}
+
+func TestInstances_InstanceExists_WithoutProviderID(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	defer ctrl.Finish()
+
+	mockedInstancesAPI := mocks.NewMockInstancesAPI(ctrl)
+
+	mockedInstancesAPI.EXPECT().List(gomock.Any(), gomock.Any()).Return([]ah.Instance{}, nil, nil)
+
+	mockedClient := &ah.APIClient{Instances: mockedInstancesAPI}
+	instances := newInstances(mockedClient)
+
+	node := &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "k8s-worker-test"}}
+
+	isExist, err := instances.InstanceExists(context.TODO(), node)
+
+	if err != nil {
+		t.Errorf("Unexpected Error: %v", err)
+	}
+
+	if isExist {
+		t.Errorf("Unexpected result")
+	}
+
+}
+
+func TestInstances_InstanceExists_DuplicateName(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	defer ctrl.Finish()
+
+	mockedInstancesAPI := mocks.NewMockInstancesAPI(ctrl)
+
+	duplicates := []ah.Instance{*testInstanceGetResponse(), *testInstanceGetResponse()}
+	duplicates[1].ID = "other-worker-id"
+	mockedInstancesAPI.EXPECT().List(gomock.Any(), gomock.Any()).Return(duplicates, nil, nil)
+
+	mockedClient := &ah.APIClient{Instances: mockedInstancesAPI}
+	instances := newInstances(mockedClient)
+
+	node := &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "k8s-worker-test"}}
+
+	isExist, err := instances.InstanceExists(context.TODO(), node)
+
+	if err == nil || err == cloudprovider.InstanceNotFound {
+		t.Errorf("Expected an ambiguity error, got: %v", err)
+	}
+
+	if isExist {
+		t.Errorf("Unexpected result")
+	}
+
+}
+
+func TestInstances_InstanceShutdown(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	defer ctrl.Finish()
+
+	mockedInstancesAPI := mocks.NewMockInstancesAPI(ctrl)
+
+	expectedInstance := &ah.Instance{
+		State: ah.InstanceShutDownStatus,
+	}
+
+	mockedInstancesAPI.EXPECT().Get(gomock.Any(), gomock.Any()).Return(expectedInstance, nil)
+
+	mockedClient := &ah.APIClient{Instances: mockedInstancesAPI}
+	instances := newInstances(mockedClient)
+
+	node := &v1.Node{Spec: v1.NodeSpec{ProviderID: "advancedhosting://test-worker-id"}}
+
+	isShutdown, err := instances.InstanceShutdown(context.TODO(), node)
+
+	if err != nil {
+		t.Errorf("Unexpected Error: %v", err)
+	}
+
+	if !isShutdown {
+		t.Errorf("Unexpected result")
+	}
+
+}
+
+func TestInstances_InstanceMetadata(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	defer ctrl.Finish()
+
+	mockedInstancesAPI := mocks.NewMockInstancesAPI(ctrl)
+
+	mockedInstancesAPI.EXPECT().List(gomock.Any(), gomock.Any()).Return(testInstanceListResponse(), nil, nil)
+
+	mockedClient := &ah.APIClient{Instances: mockedInstancesAPI}
+	instances := newInstances(mockedClient)
+
+	node := &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "k8s-worker-test"}}
+
+	metadata, err := instances.InstanceMetadata(context.TODO(), node)
+
+	expectedResult := &cloudprovider.InstanceMetadata{
+		ProviderID:   "advancedhosting://test-worker-id",
+		InstanceType: "test-slug",
+		NodeAddresses: []v1.NodeAddress{
+			{
+				Type:    v1.NodeHostName,
+				Address: "k8s-worker-test",
+			},
+			{
+				Type:    v1.NodeExternalIP,
+				Address: "1.2.3.4",
+			},
+			{
+				Type:    v1.NodeInternalIP,
+				Address: "1.0.0.1",
+			},
+		},
+	}
+
+	if err != nil {
+		t.Errorf("Unexpected Error: %v", err)
+	}
+
+	if !reflect.DeepEqual(expectedResult, metadata) {
+		t.Errorf("Unexpected result, expected %v. got: %v", expectedResult, metadata)
+	}
+
+}
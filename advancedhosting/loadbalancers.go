@@ -24,11 +24,20 @@ import (
 
 	"github.com/advancedhosting/advancedhosting-api-go/ah"
 	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
 	cloudprovider "k8s.io/cloud-provider"
+	servicehelpers "k8s.io/cloud-provider/service/helpers"
+	"k8s.io/klog"
 )
 
 const (
 	loadBalancerActiveStatus = "active"
+
+	// clusterIDSeparator separates the cluster ID from the name of the load balancers owned by the cluster
+	clusterIDSeparator = "--"
 )
 
 const (
@@ -64,15 +73,120 @@ const (
 
 	// ServiceAnnotationLoadBalancerHealthCheckPort is the health check port of the AH Managed Loadbalancer
 	ServiceAnnotationLoadBalancerHealthCheckPort = "service.beta.kubernetes.io/ah-loadbalancer-healthcheck-port"
+
+	// serviceAnnotationLoadBalancerHealthCheckPrefix is the prefix of the health check annotations.
+	// Port-scoped annotations insert a service port name or number after it,
+	// e.g. service.beta.kubernetes.io/ah-loadbalancer-healthcheck-80-type
+	serviceAnnotationLoadBalancerHealthCheckPrefix = "service.beta.kubernetes.io/ah-loadbalancer-healthcheck-"
+
+	// ServiceAnnotationLoadBalancerInternal creates the AH Managed Loadbalancer in the cluster private network only, without a public IP
+	ServiceAnnotationLoadBalancerInternal = "service.beta.kubernetes.io/ah-loadbalancer-internal"
+
+	// ServiceAnnotationLoadBalancerIP is a reserved IP address assigned to the AH Managed Loadbalancer, it takes precedence over spec.loadBalancerIP
+	ServiceAnnotationLoadBalancerIP = "service.beta.kubernetes.io/ah-loadbalancer-ip"
+
+	// ServiceAnnotationLoadBalancerRetainIP keeps the public IP of the AH Managed Loadbalancer in the account when it is deleted
+	ServiceAnnotationLoadBalancerRetainIP = "service.beta.kubernetes.io/ah-loadbalancer-retain-ip"
+
+	// ServiceAnnotationLoadBalancerRetainedIP is set by the CCM to the IP addresses kept on deletion of the AH Managed Loadbalancer.
+	// It is lost with the service when the service itself is deleted
+	ServiceAnnotationLoadBalancerRetainedIP = "service.beta.kubernetes.io/ah-loadbalancer-retained-ip"
+
+	// ServiceAnnotationLoadBalancerTLSPorts is the list of ports terminating TLS on the AH Managed Loadbalancer
+	ServiceAnnotationLoadBalancerTLSPorts = "service.beta.kubernetes.io/ah-loadbalancer-tls-ports"
+
+	// ServiceAnnotationLoadBalancerCertificateID is the AH certificate ID used to terminate TLS on the AH Managed Loadbalancer
+	ServiceAnnotationLoadBalancerCertificateID = "service.beta.kubernetes.io/ah-loadbalancer-certificate-id"
+
+	// ServiceAnnotationLoadBalancerTLSSecret is the Kubernetes TLS Secret used to terminate TLS on the AH Managed Loadbalancer
+	ServiceAnnotationLoadBalancerTLSSecret = "service.beta.kubernetes.io/ah-loadbalancer-tls-secret"
+
+	// ServiceAnnotationLoadBalancerProtocols maps port names or numbers to the forwarding rule protocols of the AH Managed Loadbalancer,
+	// e.g. "80=http,metrics=http:tcp" where the optional second protocol is the communication protocol
+	ServiceAnnotationLoadBalancerProtocols = "service.beta.kubernetes.io/ah-loadbalancer-protocols"
+
+	// ServiceAnnotationLoadBalancerProxyProtocol is the list of ports sending the PROXY protocol header to the backends of the AH Managed Loadbalancer
+	ServiceAnnotationLoadBalancerProxyProtocol = "service.beta.kubernetes.io/ah-loadbalancer-proxy-protocol"
+
+	// ServiceAnnotationLoadBalancerStickySessionsCookie is the name of the cookie used for sticky sessions on the AH Managed Loadbalancer
+	ServiceAnnotationLoadBalancerStickySessionsCookie = "service.beta.kubernetes.io/ah-loadbalancer-sticky-sessions-cookie"
+
+	// ServiceAnnotationLoadBalancerNodeSelector is a label selector of the nodes added as backends of the AH Managed Loadbalancer,
+	// e.g. "node-role.kubernetes.io/edge=true"
+	ServiceAnnotationLoadBalancerNodeSelector = "service.beta.kubernetes.io/ah-loadbalancer-node-selector"
+
+	// ServiceAnnotationLoadBalancerExcludeUnreadyNodes excludes NotReady and cordoned nodes from the backends of the AH Managed Loadbalancer
+	ServiceAnnotationLoadBalancerExcludeUnreadyNodes = "service.beta.kubernetes.io/ah-loadbalancer-exclude-unready-nodes"
+
+	// ServiceAnnotationLoadBalancerDrainingTimeout is the number of seconds a backend node of the AH Managed Loadbalancer
+	// is drained before it is removed from the load balancer
+	ServiceAnnotationLoadBalancerDrainingTimeout = "service.beta.kubernetes.io/ah-loadbalancer-draining-timeout"
+
+	// ServiceAnnotationLoadBalancerBackendWeight is the source of the backend node weights of the AH Managed Loadbalancer,
+	// either "cpu" for the allocatable CPU of the node or "label:<key>" for the value of a node label
+	ServiceAnnotationLoadBalancerBackendWeight = "service.beta.kubernetes.io/ah-loadbalancer-backend-weight"
 )
 
+// unsupportedAnnotations are reserved for features the AH API does not offer yet.
+// A service using one of them fails to sync rather than getting a load balancer
+// that silently behaves differently from what was asked.
+var unsupportedAnnotations = []struct {
+	annotation string
+	reason     string
+}{
+	{ServiceAnnotationLoadBalancerTLSPorts, "AH load balancers can not terminate TLS"},
+	{ServiceAnnotationLoadBalancerCertificateID, "AH load balancers can not terminate TLS"},
+	{ServiceAnnotationLoadBalancerTLSSecret, "AH load balancers can not terminate TLS"},
+	{ServiceAnnotationLoadBalancerProxyProtocol, "AH load balancers can not send the PROXY protocol header"},
+	{ServiceAnnotationLoadBalancerStickySessionsCookie, "AH load balancers have no cookie based sticky sessions"},
+	{ServiceAnnotationLoadBalancerBackendWeight, "AH load balancers have no backend node weights"},
+	{ServiceAnnotationLoadBalancerDrainingTimeout, "AH backend nodes can not be disabled or drained"},
+}
+
+// forwardingRuleProtocols are the protocols supported by the forwarding rules of AH load balancers
+var forwardingRuleProtocols = map[string]bool{
+	"http": true,
+	"tcp":  true,
+	"udp":  true,
+}
+
+// healthCheckFields maps the suffixes of port-scoped health check annotations to the matching
+// global annotation. A suffix that ends with another one comes first.
+var healthCheckFields = []struct {
+	suffix     string
+	annotation string
+}{
+	{"unhealthy-threshold", ServiceAnnotationLoadBalancerHealthCheckUnhealthyThreshold},
+	{"healthy-threshold", ServiceAnnotationLoadBalancerHealthCheckHealthyThreshold},
+	{"type", ServiceAnnotationLoadBalancerHealthCheckType},
+	{"url", ServiceAnnotationLoadBalancerHealthCheckURL},
+	{"interval", ServiceAnnotationLoadBalancerHealthCheckInterval},
+	{"timeout", ServiceAnnotationLoadBalancerHealthCheckTimeout},
+	{"port", ServiceAnnotationLoadBalancerHealthCheckPort},
+}
+
+// portProtocols holds the protocols of the forwarding rule of a service port
+type portProtocols struct {
+	request       string
+	communication string
+}
+
 type loadbalancers struct {
 	client      *ah.APIClient
 	clusterInfo *clusterInfo
+	instances   *instances
+	pending     *pendingOperations
+	warnings    *reportedWarnings
 }
 
 func newLoadbalancers(client *ah.APIClient, clusterInfo *clusterInfo) *loadbalancers {
-	return &loadbalancers{client: client, clusterInfo: clusterInfo}
+	return &loadbalancers{
+		client:      client,
+		clusterInfo: clusterInfo,
+		instances:   newInstances(client),
+		pending:     newPendingOperations(clusterInfo.Polling),
+		warnings:    newReportedWarnings(),
+	}
 }
 
 // GetLoadBalancer returns whether the specified load balancer exists, and
@@ -94,7 +208,7 @@ func (l *loadbalancers) GetLoadBalancer(ctx context.Context, clusterName string,
 		return nil, false, err
 	}
 
-	return l.loadBalancerStatus(loadBalancer), true, nil
+	return l.loadBalancerStatus(service, loadBalancer), true, nil
 }
 
 // GetLoadBalancerName returns the name of the load balancer. Implementations must treat the
@@ -109,6 +223,26 @@ func (l *loadbalancers) GetLoadBalancerName(ctx context.Context, clusterName str
 // Parameter 'clusterName' is the name of the cluster as presented to kube-controller-manager
 func (l *loadbalancers) EnsureLoadBalancer(ctx context.Context, clusterName string, service *v1.Service, nodes []*v1.Node) (*v1.LoadBalancerStatus, error) {
 
+	if err := l.checkSourceRanges(service); err != nil {
+		l.eventf(service, v1.EventTypeWarning, eventReasonUnsupportedConfiguration, "%v", err)
+		return nil, err
+	}
+
+	if err := l.checkUnsupportedAnnotations(service); err != nil {
+		l.eventf(service, v1.EventTypeWarning, eventReasonUnsupportedConfiguration, "%v", err)
+		return nil, err
+	}
+
+	if err := l.validateAnnotations(service); err != nil {
+		l.eventf(service, v1.EventTypeWarning, eventReasonInvalidAnnotations, "%v", err)
+		return nil, err
+	}
+
+	if l.warnings.report(service.UID, eventReasonSessionAffinityNotEnforced, service.Spec.SessionAffinity == v1.ServiceAffinityClientIP) {
+		klog.Warningf("Service %s/%s uses ClientIP session affinity, which AH load balancers do not support", service.Namespace, service.Name)
+		l.eventf(service, v1.EventTypeWarning, eventReasonSessionAffinityNotEnforced, "ClientIP session affinity is not enforced by the load balancer")
+	}
+
 	lbID := l.loadBalancerID(service)
 
 	var loadBalancer *ah.LoadBalancer
@@ -117,12 +251,19 @@ func (l *loadbalancers) EnsureLoadBalancer(ctx context.Context, clusterName stri
 
 	switch err {
 	case ah.ErrResourceNotFound:
-		loadBalancer, err = l.createLoadBalancer(ctx, service, nodes)
-		if err != nil {
-			return nil, fmt.Errorf("Error creating load balancer: %v", err)
+		loadBalancer, err = l.adoptLoadBalancer(ctx, service)
+		if err == ah.ErrResourceNotFound {
+			loadBalancer, err = l.createLoadBalancer(ctx, service, nodes)
+			if err != nil {
+				return nil, fmt.Errorf("Error creating load balancer: %v", err)
+			}
+		} else if err != nil {
+			return nil, fmt.Errorf("Error adopting load balancer: %v", err)
 		}
 	case nil:
-
+		if err = l.checkOwnership(loadBalancer); err != nil {
+			return nil, err
+		}
 	default:
 		return nil, err
 	}
@@ -131,11 +272,15 @@ func (l *loadbalancers) EnsureLoadBalancer(ctx context.Context, clusterName stri
 		return nil, fmt.Errorf("Load balancer is not active yet: %s", loadBalancer.State)
 	}
 
+	if err = l.checkInternal(service, loadBalancer); err != nil {
+		return nil, err
+	}
+
 	if err = l.updateLoadBalancer(ctx, service, nodes, loadBalancer); err != nil {
 		return nil, fmt.Errorf("Error updating load balancer: %v", err)
 	}
 
-	return l.loadBalancerStatus(loadBalancer), nil
+	return l.loadBalancerStatus(service, loadBalancer), nil
 }
 
 // UpdateLoadBalancer updates hosts under the specified load balancer.
@@ -143,6 +288,11 @@ func (l *loadbalancers) EnsureLoadBalancer(ctx context.Context, clusterName stri
 // parameters as read-only and not modify them.
 // Parameter 'clusterName' is the name of the cluster as presented to kube-controller-manager
 func (l *loadbalancers) UpdateLoadBalancer(ctx context.Context, clusterName string, service *v1.Service, nodes []*v1.Node) error {
+	if err := l.validateAnnotations(service); err != nil {
+		l.eventf(service, v1.EventTypeWarning, eventReasonInvalidAnnotations, "%v", err)
+		return err
+	}
+
 	lbID := l.loadBalancerID(service)
 
 	var loadBalancer *ah.LoadBalancer
@@ -153,10 +303,18 @@ func (l *loadbalancers) UpdateLoadBalancer(ctx context.Context, clusterName stri
 		return err
 	}
 
+	if err = l.checkOwnership(loadBalancer); err != nil {
+		return err
+	}
+
 	if loadBalancer.State != loadBalancerActiveStatus {
 		return fmt.Errorf("Load balancer is not active yet: %s", loadBalancer.State)
 	}
 
+	if err = l.checkInternal(service, loadBalancer); err != nil {
+		return err
+	}
+
 	return l.updateLoadBalancer(ctx, service, nodes, loadBalancer)
 
 }
@@ -170,6 +328,8 @@ func (l *loadbalancers) UpdateLoadBalancer(ctx context.Context, clusterName stri
 // Implementations must treat the *v1.Service parameter as read-only and not modify it.
 // Parameter 'clusterName' is the name of the cluster as presented to kube-controller-manager
 func (l *loadbalancers) EnsureLoadBalancerDeleted(ctx context.Context, clusterName string, service *v1.Service) error {
+	l.warnings.forget(service.UID)
+
 	lbID := l.loadBalancerID(service)
 
 	var loadBalancer *ah.LoadBalancer
@@ -178,6 +338,9 @@ func (l *loadbalancers) EnsureLoadBalancerDeleted(ctx context.Context, clusterNa
 
 	switch err {
 	case ah.ErrResourceNotFound:
+		if lbID != "" {
+			l.eventf(service, v1.EventTypeNormal, eventReasonDeletedLoadBalancer, "Load balancer %s has been deleted", lbID)
+		}
 		return nil
 	case nil:
 		break
@@ -185,10 +348,27 @@ func (l *loadbalancers) EnsureLoadBalancerDeleted(ctx context.Context, clusterNa
 		return err
 	}
 
+	// Keep the finalizer of the service: the load balancer would leak otherwise,
+	// the garbage collector does not see load balancers the cluster does not own.
+	if err = l.checkOwnership(loadBalancer); err != nil {
+		klog.Warningf("%v, refusing to delete it", err)
+		l.eventf(service, v1.EventTypeWarning, eventReasonLoadBalancerNotOwned, "%v, rename it with the cluster prefix or delete it manually", err)
+		return err
+	}
+
 	if loadBalancer.State == "deleting" {
+		l.eventf(service, v1.EventTypeNormal, eventReasonDeletingLoadBalancer, "Waiting for load balancer %s to be deleted", loadBalancer.ID)
 		return fmt.Errorf("Load balancer is already in deletion state")
 	}
 
+	if l.loadBalancerRetainIP(service) {
+		if err = l.retainIPAddresses(ctx, service, loadBalancer); err != nil {
+			return err
+		}
+	}
+
+	l.pending.forget(loadBalancer.ID)
+
 	if err = l.client.LoadBalancers.Delete(ctx, loadBalancer.ID); err != nil {
 		if err == ah.ErrResourceNotFound {
 			return nil
@@ -196,6 +376,8 @@ func (l *loadbalancers) EnsureLoadBalancerDeleted(ctx context.Context, clusterNa
 		return fmt.Errorf("Error deleting load balancer: %v", err)
 	}
 
+	l.eventf(service, v1.EventTypeNormal, eventReasonDeletingLoadBalancer, "Deletion of load balancer %s has been started", loadBalancer.ID)
+
 	return fmt.Errorf("LB deletion has been started")
 
 }
@@ -215,14 +397,85 @@ func (l *loadbalancers) loadBalancerByID(ctx context.Context, lbID string) (*ah.
 	return loadBalancer, nil
 }
 
-func (l *loadbalancers) loadBalancerStatus(loadBalancer *ah.LoadBalancer) *v1.LoadBalancerStatus {
-	return &v1.LoadBalancerStatus{
-		Ingress: []v1.LoadBalancerIngress{
-			{
-				IP: loadBalancer.IPAddresses[0].Address,
-			},
-		},
+// loadBalancerNamePrefix returns the prefix that marks load balancers owned by the cluster.
+func (l *loadbalancers) loadBalancerNamePrefix() string {
+	if l.clusterInfo.ClusterID == "" {
+		return ""
+	}
+	return l.clusterInfo.ClusterID + clusterIDSeparator
+}
+
+func (l *loadbalancers) ownedByCluster(loadBalancer *ah.LoadBalancer) bool {
+	return strings.HasPrefix(loadBalancer.Name, l.loadBalancerNamePrefix())
+}
+
+func (l *loadbalancers) checkOwnership(loadBalancer *ah.LoadBalancer) error {
+	if !l.ownedByCluster(loadBalancer) {
+		return fmt.Errorf("Load balancer %s is not owned by cluster %s", loadBalancer.ID, l.clusterInfo.ClusterID)
 	}
+	return nil
+}
+
+// loadBalancerStatus reports the public IP of the load balancer, or its private IP
+// for an internal load balancer. A public load balancer without a public IP,
+// not assigned yet or released, is reported without an ingress.
+func (l *loadbalancers) loadBalancerStatus(service *v1.Service, loadBalancer *ah.LoadBalancer) *v1.LoadBalancerStatus {
+	status := &v1.LoadBalancerStatus{}
+
+	if !l.loadBalancerInternal(service) {
+		if len(loadBalancer.IPAddresses) > 0 {
+			status.Ingress = append(status.Ingress, v1.LoadBalancerIngress{IP: loadBalancer.IPAddresses[0].Address})
+		}
+		return status
+	}
+
+	for _, privateNetwork := range loadBalancer.PrivateNetworks {
+		if len(privateNetwork.Addresses) > 0 {
+			status.Ingress = append(status.Ingress, v1.LoadBalancerIngress{IP: privateNetwork.Addresses[0].Address})
+			break
+		}
+	}
+
+	return status
+}
+
+// checkSourceRanges fails when the service restricts the client addresses.
+// AH load balancers have no access control, so the restriction can not be
+// enforced and the load balancer would be reachable from anywhere.
+func (l *loadbalancers) checkSourceRanges(service *v1.Service) error {
+	sourceRanges, err := servicehelpers.GetLoadBalancerSourceRanges(service)
+	if err != nil {
+		return fmt.Errorf("Invalid load balancer source ranges: %v", err)
+	}
+	if !servicehelpers.IsAllowAll(sourceRanges) {
+		return fmt.Errorf("Load balancer source ranges %s are not supported, AH load balancers can not restrict client addresses", strings.Join(sourceRanges.StringSlice(), ", "))
+	}
+	return nil
+}
+
+func (l *loadbalancers) checkUnsupportedAnnotations(service *v1.Service) error {
+	for _, unsupported := range unsupportedAnnotations {
+		if _, ok := service.Annotations[unsupported.annotation]; ok {
+			return fmt.Errorf("Annotation %s is not supported, %s", unsupported.annotation, unsupported.reason)
+		}
+	}
+	return nil
+}
+
+// checkInternal fails when the service asks for an internal load balancer while the
+// load balancer has a public IP, which requires the load balancer to be recreated.
+// A public load balancer without a public IP can not be told apart from an internal
+// one: it may not have its IP yet or have lost it, so it is only reported when that happens.
+func (l *loadbalancers) checkInternal(service *v1.Service, loadBalancer *ah.LoadBalancer) error {
+	internal := l.loadBalancerInternal(service)
+	if l.warnings.report(service.UID, eventReasonNoPublicIPAddress, !internal && len(loadBalancer.IPAddresses) == 0) {
+		klog.Warningf("Load balancer %s of service %s/%s has no public IP address", loadBalancer.ID, service.Namespace, service.Name)
+		l.eventf(service, v1.EventTypeWarning, eventReasonNoPublicIPAddress, "Load balancer %s has no public IP address, if it was created internal it can not be made public, recreate the service", loadBalancer.ID)
+	}
+	if internal && len(loadBalancer.IPAddresses) > 0 {
+		return fmt.Errorf("Load balancer %s has a public IP and can not be made internal, recreate the service", loadBalancer.ID)
+	}
+	return nil
 }
 
 func (l *loadbalancers) createLoadBalancer(ctx context.Context, service *v1.Service, nodes []*v1.Node) (*ah.LoadBalancer, error) {
@@ -237,34 +490,135 @@ func (l *loadbalancers) createLoadBalancer(ctx context.Context, service *v1.Serv
 		return nil, fmt.Errorf("API LoadBalancers.Create error: %v", err)
 	}
 
-	patcher := newServicePatcher(l.clusterInfo.kclient, service)
-	annotateService(service, ServiceAnnotationLoadBalancerID, loadBalancer.ID)
-	if err = patcher.Patch(ctx); err != nil {
+	l.eventf(service, v1.EventTypeNormal, eventReasonCreatingLoadBalancer, "Creating load balancer %s (%s)", loadBalancer.Name, loadBalancer.ID)
+
+	if err = l.setLoadBalancerID(ctx, service, loadBalancer.ID); err != nil {
 		return nil, err
 	}
 
 	return loadBalancer, nil
 }
 
+// adoptLoadBalancer looks up a load balancer owned by the cluster under the
+// expected name and stores its ID in the service annotations. It makes creation
+// idempotent when the service could not be patched after a previous create call.
+func (l *loadbalancers) adoptLoadBalancer(ctx context.Context, service *v1.Service) (*ah.LoadBalancer, error) {
+	// Without a cluster ID every load balancer of the account looks owned by the cluster,
+	// only the default name derived from the service UID is specific enough to be adopted.
+	if _, ok := service.Annotations[ServiceAnnotationLoadBalancerName]; ok && l.clusterInfo.ClusterID == "" {
+		return nil, ah.ErrResourceNotFound
+	}
+
+	claimedIDs, err := l.claimedLoadBalancerIDs(ctx, service)
+	if err != nil {
+		return nil, err
+	}
+
+	loadBalancer, err := l.loadBalancerByName(ctx, l.loadBalancerName(service), claimedIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	klog.Infof("Adopting load balancer %s (%s) for service %s/%s", loadBalancer.ID, loadBalancer.Name, service.Namespace, service.Name)
+	l.eventf(service, v1.EventTypeNormal, eventReasonAdoptingLoadBalancer, "Adopting load balancer %s (%s)", loadBalancer.Name, loadBalancer.ID)
+
+	if err = l.setLoadBalancerID(ctx, service, loadBalancer.ID); err != nil {
+		return nil, err
+	}
+
+	return loadBalancer, nil
+}
+
+// claimedLoadBalancerIDs returns the IDs of the load balancers referenced by the other services.
+// Several services may share a load balancer name, each of them must keep its own load balancer.
+func (l *loadbalancers) claimedLoadBalancerIDs(ctx context.Context, service *v1.Service) (sets.String, error) {
+	services, err := l.clusterInfo.kclient.CoreV1().Services(metav1.NamespaceAll).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	claimedIDs := sets.NewString()
+	for i := range services.Items {
+		other := &services.Items[i]
+		if other.Namespace == service.Namespace && other.Name == service.Name {
+			continue
+		}
+		if lbID := l.loadBalancerID(other); lbID != "" {
+			claimedIDs.Insert(lbID)
+		}
+	}
+	return claimedIDs, nil
+}
+
+// loadBalancerByName returns the load balancer owned by the cluster under the name, skipping the claimed ones.
+func (l *loadbalancers) loadBalancerByName(ctx context.Context, name string, claimedIDs sets.String) (*ah.LoadBalancer, error) {
+	loadBalancers, err := l.client.LoadBalancers.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var found []ah.LoadBalancer
+	for _, loadBalancer := range loadBalancers {
+		if loadBalancer.Name == name && l.ownedByCluster(&loadBalancer) && !claimedIDs.Has(loadBalancer.ID) {
+			found = append(found, loadBalancer)
+		}
+	}
+
+	switch len(found) {
+	case 0:
+		return nil, ah.ErrResourceNotFound
+	case 1:
+		return &found[0], nil
+	default:
+		return nil, fmt.Errorf("found %d load balancers named %s", len(found), name)
+	}
+}
+
+func (l *loadbalancers) setLoadBalancerID(ctx context.Context, service *v1.Service, lbID string) error {
+	patcher := newServicePatcher(l.clusterInfo.kclient, service)
+	annotateService(service, ServiceAnnotationLoadBalancerID, lbID)
+	return patcher.Patch(ctx)
+}
+
 func (l *loadbalancers) makeLoadBalancerCreateRequest(ctx context.Context, service *v1.Service, nodes []*v1.Node) (*ah.LoadBalancerCreateRequest, error) {
 	request := &ah.LoadBalancerCreateRequest{
 		Name:                  l.loadBalancerName(service),
 		DatacenterID:          l.clusterInfo.DatacenterID,
-		CreatePublicIPAddress: true,
+		CreatePublicIPAddress: !l.loadBalancerInternal(service),
 		PrivateNetworkIDs:     []string{l.clusterInfo.PrivateNetworkID},
 		BalancingAlgorithm:    l.loadBalancerBalancingAlgorithm(service),
-		ForwardingRules:       l.loadBalancerForwardingRules(service),
+	}
+
+	forwardingRules, err := l.loadBalancerForwardingRules(service)
+	if err != nil {
+		return nil, err
+	}
+	request.ForwardingRules = forwardingRules
+
+	if address := l.loadBalancerIP(service); address != "" {
+		ipAddress, err := l.reservedIPAddress(ctx, address, "")
+		if err != nil {
+			l.eventf(service, v1.EventTypeWarning, eventReasonReservedIPAddressFailed, "%v", err)
+			return nil, err
+		}
+		request.CreatePublicIPAddress = false
+		request.IPAddressIDs = []string{ipAddress.ID}
 	}
 
 	if l.loadBalancerHealthChecksEnabled(service) {
-		healthCheck, err := l.loadBalancerHealthCheckRequest(service)
+		healthChecks, err := l.loadBalancerHealthCheckRequests(service)
 		if err != nil {
 			return nil, err
 		}
-		request.HealthChecks = []ah.LBHealthCheckCreateRequest{*healthCheck}
+		request.HealthChecks = healthChecks
+	}
+
+	nodes, err = l.loadBalancerNodes(service, nodes)
+	if err != nil {
+		return nil, err
 	}
 
-	backendNodes, err := l.loadBalancerBackendNodes(nodes)
+	backendNodes, err := l.loadBalancerBackendNodes(ctx, service, nodes)
 	if err != nil {
 		return nil, err
 	}
@@ -275,64 +629,327 @@ func (l *loadbalancers) makeLoadBalancerCreateRequest(ctx context.Context, servi
 
 func (l *loadbalancers) loadBalancerName(service *v1.Service) string {
 	if name, ok := service.Annotations[ServiceAnnotationLoadBalancerName]; ok {
-		return name
+		return l.loadBalancerNamePrefix() + name
 	}
-	return cloudprovider.DefaultLoadBalancerName(service)
+	return l.loadBalancerNamePrefix() + cloudprovider.DefaultLoadBalancerName(service)
 }
 
 func (l *loadbalancers) loadBalancerBalancingAlgorithm(service *v1.Service) string {
 	if v, ok := service.Annotations[ServiceAnnotationLoadBalancerBalancingAlgorithm]; ok {
 		return v
 	}
-	return "round_robin"
+	if l.clusterInfo.LoadBalancer.BalancingAlgorithm != "" {
+		return l.clusterInfo.LoadBalancer.BalancingAlgorithm
+	}
+	return defaultBalancingAlgorithm
 }
 
-func (l *loadbalancers) loadBalancerForwardingRules(service *v1.Service) []ah.LBForwardingRuleCreateRequest {
+func (l *loadbalancers) loadBalancerForwardingRules(service *v1.Service) ([]ah.LBForwardingRuleCreateRequest, error) {
+	protocols, err := l.loadBalancerProtocols(service)
+	if err != nil {
+		return nil, err
+	}
+
 	requests := make([]ah.LBForwardingRuleCreateRequest, len(service.Spec.Ports))
 
 	for i, port := range service.Spec.Ports {
-		requests[i] = l.lbForwardingRuleCreateRequest(port)
+		requests[i] = l.lbForwardingRuleCreateRequest(port, protocols)
 	}
 
-	return requests
+	return requests, nil
+}
+
+// loadBalancerProtocols parses the protocols annotation, keyed by port name or number.
+func (l *loadbalancers) loadBalancerProtocols(service *v1.Service) (map[string]portProtocols, error) {
+	v, ok := service.Annotations[ServiceAnnotationLoadBalancerProtocols]
+	if !ok {
+		return map[string]portProtocols{}, nil
+	}
+	return parseProtocols(v)
 }
 
-func (l *loadbalancers) lbForwardingRuleCreateRequest(port v1.ServicePort) ah.LBForwardingRuleCreateRequest {
+func parseProtocols(v string) (map[string]portProtocols, error) {
+	protocols := map[string]portProtocols{}
+
+	for _, item := range strings.Split(v, ",") {
+		parts := strings.SplitN(strings.TrimSpace(item), "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("Invalid protocols %q: expected <port>=<protocol>[:<protocol>]", item)
+		}
+
+		values := strings.SplitN(strings.ToLower(parts[1]), ":", 2)
+		protocol := portProtocols{request: values[0], communication: values[0]}
+		if len(values) == 2 {
+			protocol.communication = values[1]
+		}
+
+		for _, p := range []string{protocol.request, protocol.communication} {
+			if p == "https" {
+				return nil, fmt.Errorf("Invalid protocols %q: https is not supported, AH load balancers can not terminate TLS", item)
+			}
+			if !forwardingRuleProtocols[p] {
+				return nil, fmt.Errorf("Invalid protocols %q: unsupported protocol %q", item, p)
+			}
+		}
+
+		protocols[parts[0]] = protocol
+	}
 
+	return protocols, nil
+}
+
+// lbForwardingRuleCreateRequest uses the protocols from the annotation for the port name or number,
+// then the application protocol of the port and finally the port protocol. An https application
+// protocol gets a forwarding rule of the port protocol, TLS is passed through to the backends.
+func (l *loadbalancers) lbForwardingRuleCreateRequest(port v1.ServicePort, protocols map[string]portProtocols) ah.LBForwardingRuleCreateRequest {
 	protocol := strings.ToLower(string(port.Protocol))
+	portProtocol := portProtocols{request: protocol, communication: protocol}
+
+	// an application protocol that does not run over the port protocol is ignored
+	if port.AppProtocol != nil {
+		appProtocol := strings.ToLower(*port.AppProtocol)
+		if forwardingRuleProtocols[appProtocol] && fitsPortProtocol(appProtocol, port) {
+			portProtocol = portProtocols{request: appProtocol, communication: appProtocol}
+		}
+	}
+
+	if p, ok := protocols[strconv.Itoa(int(port.Port))]; ok {
+		portProtocol = p
+	} else if p, ok := protocols[port.Name]; ok && port.Name != "" {
+		portProtocol = p
+	}
+
 	return ah.LBForwardingRuleCreateRequest{
-		RequestProtocol:       protocol,
+		RequestProtocol:       portProtocol.request,
 		RequestPort:           int(port.Port),
-		CommunicationProtocol: protocol,
+		CommunicationProtocol: portProtocol.communication,
 		CommunicationPort:     int(port.NodePort),
 	}
 }
 
-func (l *loadbalancers) loadBalancerHealthChecksEnabled(service *v1.Service) bool {
-	v, ok := service.Annotations[ServiceAnnotationLoadBalancerEnableHealthCheck]
-	if !ok {
-		return false
+// fitsPortProtocol returns true if the forwarding rule protocol runs over the protocol of the service port:
+// http and tcp over TCP, udp over UDP.
+func fitsPortProtocol(protocol string, port v1.ServicePort) bool {
+	if port.Protocol != "" && strings.ToLower(string(port.Protocol)) != "tcp" {
+		return strings.ToLower(string(port.Protocol)) == protocol
+	}
+	return protocol == "http" || protocol == "tcp"
+}
+
+func (l *loadbalancers) loadBalancerIP(service *v1.Service) string {
+	if v, ok := service.Annotations[ServiceAnnotationLoadBalancerIP]; ok {
+		return v
+	}
+	return service.Spec.LoadBalancerIP
+}
+
+// reservedIPAddress looks up the reserved IP address and makes sure
+// it is not used by an instance or by another load balancer than lbID.
+func (l *loadbalancers) reservedIPAddress(ctx context.Context, address, lbID string) (*ah.IPAddress, error) {
+	options := &ah.ListOptions{
+		Filters: []ah.FilterInterface{&ah.EqFilter{Keys: []string{"address"}, Value: address}},
+	}
+	ipAddresses, err := l.client.IPAddresses.List(ctx, options)
+	if err != nil {
+		return nil, err
+	}
+
+	var ipAddress *ah.IPAddress
+	for i := range ipAddresses {
+		if ipAddresses[i].Address == address {
+			ipAddress = &ipAddresses[i]
+			break
+		}
+	}
+	if ipAddress == nil {
+		return nil, fmt.Errorf("Reserved IP address %s is not found", address)
+	}
+
+	if len(ipAddress.InstanceIDs) > 0 {
+		return nil, fmt.Errorf("Reserved IP address %s is already in use by instance %s", address, ipAddress.InstanceIDs[0])
+	}
+
+	loadBalancers, err := l.client.LoadBalancers.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, loadBalancer := range loadBalancers {
+		if loadBalancer.ID == lbID {
+			continue
+		}
+		for _, lbIPAddress := range loadBalancer.IPAddresses {
+			if lbIPAddress.ID == ipAddress.ID || lbIPAddress.Address == address {
+				return nil, fmt.Errorf("Reserved IP address %s is already in use by load balancer %s", address, loadBalancer.ID)
+			}
+		}
+	}
+
+	return ipAddress, nil
+}
+
+func (l *loadbalancers) loadBalancerRetainIP(service *v1.Service) bool {
+	v, ok := service.Annotations[ServiceAnnotationLoadBalancerRetainIP]
+	if !ok {
+		return false
+	}
+
+	res, err := strconv.ParseBool(v)
+	if err != nil {
+		return false
+	}
+	return res
+}
+
+func (l *loadbalancers) loadBalancerInternal(service *v1.Service) bool {
+	v, ok := service.Annotations[ServiceAnnotationLoadBalancerInternal]
+	if !ok {
+		return false
+	}
+
+	res, err := strconv.ParseBool(v)
+	if err != nil {
+		return false
+	}
+	return res
+}
+
+func (l *loadbalancers) loadBalancerExcludeUnreadyNodes(service *v1.Service) bool {
+	v, ok := service.Annotations[ServiceAnnotationLoadBalancerExcludeUnreadyNodes]
+	if !ok {
+		return false
+	}
+
+	res, err := strconv.ParseBool(v)
+	if err != nil {
+		return false
+	}
+	return res
+}
+
+// loadBalancerHealthChecksEnabled returns true if the health checks are enabled with the annotation.
+// Without it, port-scoped health check annotations enable the health checks, and services
+// with externalTrafficPolicy Local get a health check on their health check node port.
+func (l *loadbalancers) loadBalancerHealthChecksEnabled(service *v1.Service) bool {
+	v, ok := service.Annotations[ServiceAnnotationLoadBalancerEnableHealthCheck]
+	if !ok {
+		if len(l.healthCheckPortAnnotations(service)) > 0 {
+			return true
+		}
+		return servicehelpers.NeedsHealthCheck(service) && service.Spec.HealthCheckNodePort != 0
+	}
+
+	res, err := strconv.ParseBool(v)
+	if err != nil {
+		return false
+	}
+	return res
+}
+
+// parseHealthCheckPortAnnotation returns the service port and the matching global annotation
+// of a port-scoped health check annotation.
+func parseHealthCheckPortAnnotation(key string) (port, annotation string, ok bool) {
+	if !strings.HasPrefix(key, serviceAnnotationLoadBalancerHealthCheckPrefix) {
+		return "", "", false
+	}
+	rest := strings.TrimPrefix(key, serviceAnnotationLoadBalancerHealthCheckPrefix)
+
+	for _, field := range healthCheckFields {
+		if rest == field.suffix {
+			return "", "", false
+		}
+		if port := strings.TrimSuffix(rest, "-"+field.suffix); port != rest && port != "" {
+			return port, field.annotation, true
+		}
+	}
+	return "", "", false
+}
+
+// healthCheckPortAnnotations returns the values of the port-scoped health check annotations
+// by service port and global annotation.
+func (l *loadbalancers) healthCheckPortAnnotations(service *v1.Service) map[string]map[string]string {
+	portAnnotations := map[string]map[string]string{}
+	for key, value := range service.Annotations {
+		if port, annotation, ok := parseHealthCheckPortAnnotation(key); ok {
+			if portAnnotations[port] == nil {
+				portAnnotations[port] = map[string]string{}
+			}
+			portAnnotations[port][annotation] = value
+		}
+	}
+	return portAnnotations
+}
+
+// loadBalancerHealthCheckRequests returns the health check configured by the global annotations,
+// or one health check per service port with port-scoped annotations. Port-scoped checks fall back
+// to the global annotations, except for the port which defaults to the node port of the service port.
+func (l *loadbalancers) loadBalancerHealthCheckRequests(service *v1.Service) ([]ah.LBHealthCheckCreateRequest, error) {
+	portAnnotations := l.healthCheckPortAnnotations(service)
+
+	if len(portAnnotations) == 0 {
+		request, err := l.loadBalancerHealthCheckRequest(l.defaultHealthCheckRequest(service), service.Annotations)
+		if err != nil {
+			return nil, err
+		}
+		return []ah.LBHealthCheckCreateRequest{*request}, nil
+	}
+
+	var requests []ah.LBHealthCheckCreateRequest
+	for _, port := range service.Spec.Ports {
+		values, ok := portAnnotations[strconv.Itoa(int(port.Port))]
+		if !ok && port.Name != "" {
+			values, ok = portAnnotations[port.Name]
+		}
+		if !ok {
+			continue
+		}
+
+		annotations := make(map[string]string, len(service.Annotations))
+		for key, value := range service.Annotations {
+			annotations[key] = value
+		}
+		annotations[ServiceAnnotationLoadBalancerHealthCheckPort] = strconv.Itoa(int(port.NodePort))
+		for key, value := range values {
+			annotations[key] = value
+		}
+
+		request, err := l.loadBalancerHealthCheckRequest(ah.LBHealthCheckCreateRequest{}, annotations)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid health check of port %d: %v", port.Port, err)
+		}
+		requests = append(requests, *request)
+	}
+
+	return requests, nil
+}
+
+// defaultHealthCheckRequest returns the health check of services with externalTrafficPolicy Local:
+// kube-proxy answers on /healthz of the health check node port whether the node has local endpoints.
+func (l *loadbalancers) defaultHealthCheckRequest(service *v1.Service) ah.LBHealthCheckCreateRequest {
+	path, port := servicehelpers.GetServiceHealthCheckPathPort(service)
+	if path == "" || port == 0 {
+		return ah.LBHealthCheckCreateRequest{}
 	}
-
-	res, err := strconv.ParseBool(v)
-	if err != nil {
-		return false
+	return ah.LBHealthCheckCreateRequest{
+		Type: "http",
+		URL:  path,
+		Port: int(port),
 	}
-	return res
 }
 
-func (l *loadbalancers) loadBalancerHealthCheckRequest(service *v1.Service) (*ah.LBHealthCheckCreateRequest, error) {
-	var request ah.LBHealthCheckCreateRequest
-
-	if v, ok := service.Annotations[ServiceAnnotationLoadBalancerHealthCheckType]; ok {
+// loadBalancerHealthCheckRequest overrides the defaults of the health check with the annotations.
+func (l *loadbalancers) loadBalancerHealthCheckRequest(request ah.LBHealthCheckCreateRequest, annotations map[string]string) (*ah.LBHealthCheckCreateRequest, error) {
+	if v, ok := annotations[ServiceAnnotationLoadBalancerHealthCheckType]; ok {
 		request.Type = v
+		if _, ok := annotations[ServiceAnnotationLoadBalancerHealthCheckURL]; !ok && v != "http" {
+			request.URL = ""
+		}
 	}
 
-	if v, ok := service.Annotations[ServiceAnnotationLoadBalancerHealthCheckURL]; ok {
+	if v, ok := annotations[ServiceAnnotationLoadBalancerHealthCheckURL]; ok {
 		request.URL = v
 	}
 
-	if v, ok := service.Annotations[ServiceAnnotationLoadBalancerHealthCheckInterval]; ok {
+	if v, ok := annotations[ServiceAnnotationLoadBalancerHealthCheckInterval]; ok {
 		interval, err := strconv.Atoi(v)
 		if err != nil {
 			return nil, fmt.Errorf("Invalid health check interval: %v", err)
@@ -340,7 +957,7 @@ func (l *loadbalancers) loadBalancerHealthCheckRequest(service *v1.Service) (*ah
 		request.Interval = interval
 	}
 
-	if v, ok := service.Annotations[ServiceAnnotationLoadBalancerHealthCheckTimeout]; ok {
+	if v, ok := annotations[ServiceAnnotationLoadBalancerHealthCheckTimeout]; ok {
 		timeout, err := strconv.Atoi(v)
 		if err != nil {
 			return nil, fmt.Errorf("Invalid health check timeout: %v", err)
@@ -348,7 +965,7 @@ func (l *loadbalancers) loadBalancerHealthCheckRequest(service *v1.Service) (*ah
 		request.Timeout = timeout
 	}
 
-	if v, ok := service.Annotations[ServiceAnnotationLoadBalancerHealthCheckUnhealthyThreshold]; ok {
+	if v, ok := annotations[ServiceAnnotationLoadBalancerHealthCheckUnhealthyThreshold]; ok {
 		unhealthyThreshold, err := strconv.Atoi(v)
 		if err != nil {
 			return nil, fmt.Errorf("Invalid health check unhealthy threshold: %v", err)
@@ -356,7 +973,7 @@ func (l *loadbalancers) loadBalancerHealthCheckRequest(service *v1.Service) (*ah
 		request.UnhealthyThreshold = unhealthyThreshold
 	}
 
-	if v, ok := service.Annotations[ServiceAnnotationLoadBalancerHealthCheckHealthyThreshold]; ok {
+	if v, ok := annotations[ServiceAnnotationLoadBalancerHealthCheckHealthyThreshold]; ok {
 		healthyThreshold, err := strconv.Atoi(v)
 		if err != nil {
 			return nil, fmt.Errorf("Invalid health check healthy threshold: %v", err)
@@ -364,7 +981,7 @@ func (l *loadbalancers) loadBalancerHealthCheckRequest(service *v1.Service) (*ah
 		request.HealthyThreshold = healthyThreshold
 	}
 
-	if v, ok := service.Annotations[ServiceAnnotationLoadBalancerHealthCheckPort]; ok {
+	if v, ok := annotations[ServiceAnnotationLoadBalancerHealthCheckPort]; ok {
 		port, err := strconv.Atoi(v)
 		if err != nil {
 			return nil, fmt.Errorf("Invalid health check port: %v", err)
@@ -375,16 +992,53 @@ func (l *loadbalancers) loadBalancerHealthCheckRequest(service *v1.Service) (*ah
 	return &request, nil
 }
 
-func (l *loadbalancers) loadBalancerBackendNodes(nodes []*v1.Node) ([]ah.LBBackendNodeCreateRequest, error) {
-	var requests []ah.LBBackendNodeCreateRequest
+// loadBalancerNodes returns the nodes matching the node selector of the service,
+// without the NotReady and cordoned ones when they are excluded.
+func (l *loadbalancers) loadBalancerNodes(service *v1.Service, nodes []*v1.Node) ([]*v1.Node, error) {
+	selector := labels.Everything()
+	if v, ok := service.Annotations[ServiceAnnotationLoadBalancerNodeSelector]; ok {
+		var err error
+		if selector, err = labels.Parse(v); err != nil {
+			return nil, fmt.Errorf("Invalid node selector %q: %v", v, err)
+		}
+	}
+	excludeUnready := l.loadBalancerExcludeUnreadyNodes(service)
+
+	var res []*v1.Node
 	for _, node := range nodes {
+		if !selector.Matches(labels.Set(node.Labels)) {
+			continue
+		}
+		if excludeUnready && (node.Spec.Unschedulable || !nodeReady(node)) {
+			continue
+		}
+		res = append(res, node)
+	}
 
-		id, err := instanceIDByProviderID(node.Spec.ProviderID)
+	if len(res) == 0 && len(nodes) > 0 {
+		l.eventf(service, v1.EventTypeWarning, eventReasonNoBackendNodes, "None of the %d nodes match the backend node selection of the load balancer", len(nodes))
+	}
 
-		if err != nil {
-			return nil, err
+	return res, nil
+}
+
+func nodeReady(node *v1.Node) bool {
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == v1.NodeReady {
+			return condition.Status == v1.ConditionTrue
 		}
+	}
+	return false
+}
+
+func (l *loadbalancers) loadBalancerBackendNodes(ctx context.Context, service *v1.Service, nodes []*v1.Node) ([]ah.LBBackendNodeCreateRequest, error) {
+	ids, err := l.backendInstanceIDs(ctx, service, nodes)
+	if err != nil {
+		return nil, err
+	}
 
+	var requests []ah.LBBackendNodeCreateRequest
+	for _, id := range ids {
 		request := ah.LBBackendNodeCreateRequest{
 			CloudServerID: id,
 		}
@@ -393,26 +1047,105 @@ func (l *loadbalancers) loadBalancerBackendNodes(nodes []*v1.Node) ([]ah.LBBacke
 	return requests, nil
 }
 
+// backendInstanceIDs returns the instance IDs of the nodes. A node without a provider ID yet,
+// e.g. while it is initializing, is looked up by name; nodes that can not be resolved are skipped
+// so that the load balancer is still reconciled with the other ones.
+func (l *loadbalancers) backendInstanceIDs(ctx context.Context, service *v1.Service, nodes []*v1.Node) ([]string, error) {
+	var ids []string
+	for _, node := range nodes {
+		if node.Spec.ProviderID == "" {
+			instance, err := l.instances.instanceByName(ctx, types.NodeName(node.Name))
+			if err == nil {
+				ids = append(ids, instance.ID)
+				continue
+			}
+			if _, ok := err.(*ambiguousInstanceNameError); ok {
+				l.skipBackendNode(service, node, "ambiguous_name", "Node %s has no provider ID and %v", node.Name, err)
+				continue
+			}
+			if err != cloudprovider.InstanceNotFound {
+				return nil, err
+			}
+			l.skipBackendNode(service, node, "missing_provider_id", "Node %s has no provider ID and no instance with its name", node.Name)
+			continue
+		}
+
+		id, err := instanceIDByProviderID(node.Spec.ProviderID)
+		if err != nil {
+			l.skipBackendNode(service, node, "invalid_provider_id", "Node %s has an invalid provider ID %q", node.Name, node.Spec.ProviderID)
+			continue
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func (l *loadbalancers) skipBackendNode(service *v1.Service, node *v1.Node, reason, messageFmt string, args ...interface{}) {
+	message := fmt.Sprintf(messageFmt, args...)
+	klog.Warningf("%s, skipping it as backend of service %s/%s", message, service.Namespace, service.Name)
+	l.eventf(service, v1.EventTypeWarning, eventReasonSkippedBackendNode, "%s, skipping it as backend node", message)
+	skippedBackendNodes.WithLabelValues(reason).Inc()
+}
+
+// updateLoadBalancer reconciles the load balancer without waiting for the API.
+// Every step submits its changes and returns a pendingOperationsError, so the
+// service is requeued and the next sync continues once the changes are applied.
 func (l *loadbalancers) updateLoadBalancer(ctx context.Context, service *v1.Service, nodes []*v1.Node, lb *ah.LoadBalancer) error {
+	if err := l.pending.check(ctx, lb.ID); err != nil {
+		if _, ok := err.(*pendingOperationTimeoutError); ok {
+			l.eventf(service, v1.EventTypeWarning, eventReasonPendingOperationTimeout, "%v", err)
+		}
+		return err
+	}
+
+	if err := l.updateIPAddresses(ctx, service, lb); err != nil {
+		return err
+	}
+
+	if err := l.pending.err(lb.ID); err != nil {
+		return err
+	}
+
 	if err := l.updateLoadBalancerInfo(ctx, service, lb); err != nil {
 		return err
 	}
 
+	if err := l.pending.err(lb.ID); err != nil {
+		return err
+	}
+
 	if err := l.updateForwardingRules(ctx, service, lb); err != nil {
 		return err
 	}
 
+	if err := l.pending.err(lb.ID); err != nil {
+		return err
+	}
+
 	if l.loadBalancerHealthChecksEnabled(service) {
 		if err := l.updateHealthChecks(ctx, service, lb); err != nil {
 			return err
 		}
 	} else {
-		if err := l.deleteHealthChecks(ctx, lb); err != nil {
+		if err := l.deleteHealthChecks(ctx, service, lb); err != nil {
 			return err
 		}
 	}
 
-	if err := l.updateBackendNodes(ctx, nodes, lb); err != nil {
+	if err := l.pending.err(lb.ID); err != nil {
+		return err
+	}
+
+	nodes, err := l.loadBalancerNodes(service, nodes)
+	if err != nil {
+		return err
+	}
+
+	if err := l.updateBackendNodes(ctx, service, nodes, lb); err != nil {
+		return err
+	}
+
+	if err := l.pending.err(lb.ID); err != nil {
 		return err
 	}
 
@@ -440,6 +1173,8 @@ func (l *loadbalancers) updateLoadBalancerInfo(ctx context.Context, service *v1.
 		return err
 	}
 
+	l.eventf(service, v1.EventTypeNormal, eventReasonUpdatingLoadBalancer, "Updating load balancer %s", lb.ID)
+
 	stateFunc := func(ctx context.Context) (state string, err error) {
 		lb, err := l.client.LoadBalancers.Get(ctx, lb.ID)
 		if err != nil {
@@ -448,32 +1183,216 @@ func (l *loadbalancers) updateLoadBalancerInfo(ctx context.Context, service *v1.
 		return lb.State, nil
 	}
 
-	if err := waitForState(ctx, stateFunc, "active"); err != nil {
+	l.pending.add(lb.ID, fmt.Sprintf("load balancer %s", lb.ID), stateFunc, "active")
+
+	return nil
+
+}
+
+// updateIPAddresses assigns the reserved IP address requested by the service
+// and releases the other IP addresses of the load balancer once it is assigned.
+func (l *loadbalancers) updateIPAddresses(ctx context.Context, service *v1.Service, lb *ah.LoadBalancer) error {
+	address := l.loadBalancerIP(service)
+	if address == "" {
+		return nil
+	}
+
+	var assigned bool
+	for _, ipAddress := range lb.IPAddresses {
+		if ipAddress.Address == address {
+			assigned = true
+			break
+		}
+	}
+
+	if !assigned {
+		ipAddress, err := l.reservedIPAddress(ctx, address, lb.ID)
+		if err != nil {
+			l.eventf(service, v1.EventTypeWarning, eventReasonReservedIPAddressFailed, "%v", err)
+			return err
+		}
+		if err := l.assignIPAddress(ctx, lb.ID, ipAddress.ID); err != nil {
+			return err
+		}
+		l.eventf(service, v1.EventTypeNormal, eventReasonAssigningIPAddress, "Assigning IP address %s", address)
+		return nil
+	}
+
+	// A released address stays in the account. The previous addresses are deleted once released,
+	// except for delete protected ones, which are reserved addresses the service used before.
+	for _, ipAddress := range lb.IPAddresses {
+		if ipAddress.Address == address {
+			continue
+		}
+
+		reserved, err := l.deleteProtectedIPAddress(ctx, ipAddress.ID)
+		if err != nil {
+			return err
+		}
+
+		if reserved {
+			if err := l.releaseIPAddress(ctx, lb.ID, ipAddress.ID, nil); err != nil {
+				return err
+			}
+			l.eventf(service, v1.EventTypeNormal, eventReasonReleasingIPAddress, "Releasing IP address %s, it is delete protected and stays in the account", ipAddress.Address)
+			continue
+		}
+
+		ipAddress := ipAddress
+		onReleased := func(ctx context.Context) error {
+			return l.deleteIPAddress(ctx, service, ipAddress)
+		}
+		if err := l.releaseIPAddress(ctx, lb.ID, ipAddress.ID, onReleased); err != nil {
+			return err
+		}
+		l.eventf(service, v1.EventTypeNormal, eventReasonReleasingIPAddress, "Releasing IP address %s, it is deleted from the account once released", ipAddress.Address)
+	}
+
+	return nil
+}
+
+// deleteProtectedIPAddress returns true if the IP address is delete protected in the account.
+func (l *loadbalancers) deleteProtectedIPAddress(ctx context.Context, ipID string) (bool, error) {
+	ipAddress, err := l.client.IPAddresses.Get(ctx, ipID)
+	if err != nil {
+		if err == ah.ErrResourceNotFound {
+			return false, nil
+		}
+		return false, err
+	}
+	return ipAddress.DeleteProtection, nil
+}
+
+func (l *loadbalancers) deleteIPAddress(ctx context.Context, service *v1.Service, ipAddress ah.LBIPAddress) error {
+	if err := l.client.IPAddresses.Delete(ctx, ipAddress.ID); err != nil && err != ah.ErrResourceNotFound {
+		return err
+	}
+	klog.Infof("Deleted previous IP address %s (ID %s) of service %s/%s", ipAddress.Address, ipAddress.ID, service.Namespace, service.Name)
+	l.eventf(service, v1.EventTypeNormal, eventReasonDeletingIPAddress, "Deleting previous IP address %s (ID %s)", ipAddress.Address, ipAddress.ID)
+	return nil
+}
+
+// retainIPAddresses releases the IP addresses of the load balancer before it is deleted,
+// so that they stay in the account. The kept addresses are recorded in the service annotations,
+// which do not outlive a deleted service, so they are logged and reported in events as well.
+func (l *loadbalancers) retainIPAddresses(ctx context.Context, service *v1.Service, lb *ah.LoadBalancer) error {
+	if err := l.pending.check(ctx, lb.ID); err != nil {
+		return err
+	}
+
+	if len(lb.IPAddresses) == 0 {
+		return nil
+	}
+
+	addresses := make([]string, len(lb.IPAddresses))
+	for i, ipAddress := range lb.IPAddresses {
+		addresses[i] = ipAddress.Address
+	}
+
+	patcher := newServicePatcher(l.clusterInfo.kclient, service)
+	annotateService(service, ServiceAnnotationLoadBalancerRetainedIP, strings.Join(addresses, ","))
+	if err := patcher.Patch(ctx); err != nil {
+		return err
+	}
+
+	for _, ipAddress := range lb.IPAddresses {
+		klog.Infof("Retaining IP address %s (ID %s) of load balancer %s for service %s/%s, it stays in the account", ipAddress.Address, ipAddress.ID, lb.ID, service.Namespace, service.Name)
+		if err := l.releaseIPAddress(ctx, lb.ID, ipAddress.ID, nil); err != nil {
+			return err
+		}
+		l.eventf(service, v1.EventTypeNormal, eventReasonRetainingIPAddress, "Retaining IP address %s (ID %s)", ipAddress.Address, ipAddress.ID)
+	}
+
+	return l.pending.err(lb.ID)
+}
+
+func (l *loadbalancers) assignIPAddress(ctx context.Context, lbID, ipID string) error {
+	if _, err := l.client.LoadBalancers.AssignIPAddresses(ctx, lbID, []string{ipID}); err != nil {
 		return err
 	}
 
+	stateFunc := func(ctx context.Context) (state string, err error) {
+		ipAddress, err := l.client.LoadBalancers.GetIPAddress(ctx, lbID, ipID)
+		if err != nil {
+			return "", err
+		}
+		return ipAddress.State, nil
+	}
+
+	l.pending.add(lbID, fmt.Sprintf("ip address %s", ipID), stateFunc, "active")
+
 	return nil
+}
+
+// callReleaseIPAddress calls LoadBalancers.ReleaseIPAddress, which dereferences a nil response
+// on any API or transport error and panics. The panic is turned into an error.
+func (l *loadbalancers) callReleaseIPAddress(ctx context.Context, lbID, ipID string) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("Error releasing ip address %s of load balancer %s", ipID, lbID)
+		}
+	}()
+	return l.client.LoadBalancers.ReleaseIPAddress(ctx, lbID, ipID)
+}
+
+// releaseIPAddress detaches the IP address from the load balancer, the address stays in the account.
+// onReleased, if any, is called once the address is detached and the release is finished when it succeeds.
+func (l *loadbalancers) releaseIPAddress(ctx context.Context, lbID, ipID string, onReleased func(context.Context) error) error {
+	if err := l.callReleaseIPAddress(ctx, lbID, ipID); err != nil {
+		// the error of the API is lost, an address that is not found anymore is released already
+		if _, getErr := l.client.LoadBalancers.GetIPAddress(ctx, lbID, ipID); getErr != ah.ErrResourceNotFound {
+			return err
+		}
+		klog.Infof("IP address %s of load balancer %s is released already", ipID, lbID)
+	}
+
+	stateFunc := func(ctx context.Context) (state string, err error) {
+		ipAddress, err := l.client.LoadBalancers.GetIPAddress(ctx, lbID, ipID)
+		if err != nil {
+			if err != ah.ErrResourceNotFound {
+				return "", err
+			}
+			if onReleased != nil {
+				if err := onReleased(ctx); err != nil {
+					return "", err
+				}
+			}
+			return "deleted", nil
+		}
+		return ipAddress.State, nil
+	}
 
+	l.pending.add(lbID, fmt.Sprintf("ip address %s", ipID), stateFunc, "deleted")
+
+	return nil
 }
 
 func (l *loadbalancers) updateForwardingRules(ctx context.Context, service *v1.Service, lb *ah.LoadBalancer) error {
+	requests, err := l.loadBalancerForwardingRules(service)
+	if err != nil {
+		return err
+	}
+
 	frsToDelete := make(map[int]ah.LBForwardingRule, len(lb.ForwardingRules))
 
 	for _, fr := range lb.ForwardingRules {
 		frsToDelete[fr.RequestPort] = fr
 	}
 
-	for _, port := range service.Spec.Ports {
-		fr, ok := frsToDelete[int(port.Port)]
+	for i := range requests {
+		request := &requests[i]
+		fr, ok := frsToDelete[request.RequestPort]
 		if !ok {
-			if err := l.addForwardingRule(ctx, lb.ID, &port); err != nil {
+			if err := l.addForwardingRule(ctx, lb.ID, request); err != nil {
 				return err
 			}
+			l.eventf(service, v1.EventTypeNormal, eventReasonCreatingForwardingRule, "Creating forwarding rule %s:%d -> %s:%d",
+				request.RequestProtocol, request.RequestPort, request.CommunicationProtocol, request.CommunicationPort)
 		} else {
-			if err := l.updateForwardingRule(ctx, &port, &fr, lb.ID); err != nil {
+			if err := l.updateForwardingRule(ctx, service, request, &fr, lb.ID); err != nil {
 				return err
 			}
-			delete(frsToDelete, int(port.Port))
+			delete(frsToDelete, request.RequestPort)
 		}
 
 	}
@@ -482,14 +1401,14 @@ func (l *loadbalancers) updateForwardingRules(ctx context.Context, service *v1.S
 		if err := l.removeForwardingRule(ctx, lb.ID, fr.ID); err != nil {
 			return err
 		}
+		l.eventf(service, v1.EventTypeNormal, eventReasonDeletingForwardingRule, "Deleting forwarding rule %s:%d", fr.RequestProtocol, fr.RequestPort)
 	}
 
 	return nil
 }
 
-func (l *loadbalancers) addForwardingRule(ctx context.Context, lbID string, port *v1.ServicePort) error {
-	request := l.lbForwardingRuleCreateRequest(*port)
-	fr, err := l.client.LoadBalancers.CreateForwardingRule(ctx, lbID, &request)
+func (l *loadbalancers) addForwardingRule(ctx context.Context, lbID string, request *ah.LBForwardingRuleCreateRequest) error {
+	fr, err := l.client.LoadBalancers.CreateForwardingRule(ctx, lbID, request)
 	if err != nil {
 		return err
 	}
@@ -502,9 +1421,7 @@ func (l *loadbalancers) addForwardingRule(ctx context.Context, lbID string, port
 		return fr.State, nil
 	}
 
-	if err := waitForState(ctx, stateFunc, "active"); err != nil {
-		return err
-	}
+	l.pending.add(lbID, fmt.Sprintf("forwarding rule %s", fr.ID), stateFunc, "active")
 
 	return nil
 }
@@ -525,114 +1442,171 @@ func (l *loadbalancers) removeForwardingRule(ctx context.Context, lbID, frID str
 		return fr.State, nil
 	}
 
-	if err := waitForState(ctx, stateFunc, "deleted"); err != nil {
-		return err
-	}
+	l.pending.add(lbID, fmt.Sprintf("forwarding rule %s", frID), stateFunc, "deleted")
 
 	return nil
 }
 
-func (l *loadbalancers) updateForwardingRule(ctx context.Context, port *v1.ServicePort, fr *ah.LBForwardingRule, lbID string) error {
-	protocol := strings.ToLower(string(port.Protocol))
-	if fr.RequestProtocol != protocol ||
-		fr.RequestPort != int(port.Port) ||
-		fr.CommunicationProtocol != protocol ||
-		fr.CommunicationPort != int(port.NodePort) {
+func (l *loadbalancers) updateForwardingRule(ctx context.Context, service *v1.Service, request *ah.LBForwardingRuleCreateRequest, fr *ah.LBForwardingRule, lbID string) error {
+	if fr.RequestProtocol != request.RequestProtocol ||
+		fr.RequestPort != request.RequestPort ||
+		fr.CommunicationProtocol != request.CommunicationProtocol ||
+		fr.CommunicationPort != request.CommunicationPort {
 
+		// The rule is created again by the next sync, once the deletion is finished.
 		if err := l.removeForwardingRule(ctx, lbID, fr.ID); err != nil {
 			return err
 		}
-
-		if err := l.addForwardingRule(ctx, lbID, port); err != nil {
-			return err
-		}
+		l.eventf(service, v1.EventTypeNormal, eventReasonDeletingForwardingRule, "Deleting forwarding rule %s:%d to recreate it", fr.RequestProtocol, fr.RequestPort)
 	}
 	return nil
 }
 
+// updateHealthChecks reconciles the health checks of the load balancer. Existing checks are matched
+// with the expected ones by port first, the remaining ones are updated in order, created or deleted.
 func (l *loadbalancers) updateHealthChecks(ctx context.Context, service *v1.Service, lb *ah.LoadBalancer) error {
-	hc, err := l.loadBalancerHealthCheckRequest(service)
+	hcs, err := l.loadBalancerHealthCheckRequests(service)
 	if err != nil {
 		return err
 	}
 
-	if len(lb.HealthChecks) == 0 {
-		healthCheck, err := l.client.LoadBalancers.CreateHealthCheck(ctx, lb.ID, hc)
+	matched := make([]bool, len(lb.HealthChecks))
+	var unmatched []*ah.LBHealthCheckCreateRequest
 
-		if err != nil {
-			return err
+	for i := range hcs {
+		found := false
+		for j := range lb.HealthChecks {
+			if !matched[j] && lb.HealthChecks[j].Port == hcs[i].Port {
+				matched[j] = true
+				found = true
+				if err := l.updateHealthCheck(ctx, service, lb.ID, &lb.HealthChecks[j], &hcs[i]); err != nil {
+					return err
+				}
+				break
+			}
 		}
+		if !found {
+			unmatched = append(unmatched, &hcs[i])
+		}
+	}
 
-		stateFunc := func(ctx context.Context) (state string, err error) {
-			hc, err := l.client.LoadBalancers.GetHealthCheck(ctx, lb.ID, healthCheck.ID)
-			if err != nil {
-				return "", err
+	for _, hc := range unmatched {
+		found := false
+		for j := range lb.HealthChecks {
+			if !matched[j] {
+				matched[j] = true
+				found = true
+				if err := l.updateHealthCheck(ctx, service, lb.ID, &lb.HealthChecks[j], hc); err != nil {
+					return err
+				}
+				break
+			}
+		}
+		if !found {
+			if err := l.createHealthCheck(ctx, service, lb.ID, hc); err != nil {
+				return err
+			}
+		}
+	}
+
+	for j := range lb.HealthChecks {
+		if !matched[j] {
+			if err := l.deleteHealthCheck(ctx, service, lb.ID, lb.HealthChecks[j].ID); err != nil {
+				return err
 			}
-			return hc.State, nil
 		}
+	}
 
-		if err := waitForState(ctx, stateFunc, "active"); err != nil {
-			return err
+	return nil
+}
+
+func (l *loadbalancers) createHealthCheck(ctx context.Context, service *v1.Service, lbID string, hc *ah.LBHealthCheckCreateRequest) error {
+	healthCheck, err := l.client.LoadBalancers.CreateHealthCheck(ctx, lbID, hc)
+
+	if err != nil {
+		return err
+	}
+
+	l.eventf(service, v1.EventTypeNormal, eventReasonCreatingHealthCheck, "Creating %s health check on port %d", hc.Type, hc.Port)
+
+	stateFunc := func(ctx context.Context) (state string, err error) {
+		hc, err := l.client.LoadBalancers.GetHealthCheck(ctx, lbID, healthCheck.ID)
+		if err != nil {
+			return "", err
 		}
+		return hc.State, nil
+	}
+
+	l.pending.add(lbID, fmt.Sprintf("health check %s", healthCheck.ID), stateFunc, "active")
+
+	return nil
+}
 
+// updateHealthCheck updates the health check if it differs from the expected one. Unset intervals
+// and thresholds are left to the API defaults, so they are not compared.
+func (l *loadbalancers) updateHealthCheck(ctx context.Context, service *v1.Service, lbID string, origHC *ah.LBHealthCheck, hc *ah.LBHealthCheckCreateRequest) error {
+	if origHC.Type == hc.Type &&
+		origHC.URL == hc.URL &&
+		healthCheckValueEqual(origHC.Interval, hc.Interval) &&
+		healthCheckValueEqual(origHC.Timeout, hc.Timeout) &&
+		healthCheckValueEqual(origHC.UnhealthyThreshold, hc.UnhealthyThreshold) &&
+		healthCheckValueEqual(origHC.HealthyThreshold, hc.HealthyThreshold) &&
+		origHC.Port == hc.Port {
 		return nil
+	}
 
+	request := &ah.LBHealthCheckUpdateRequest{
+		Type:               hc.Type,
+		URL:                hc.URL,
+		Interval:           hc.Interval,
+		Timeout:            hc.Timeout,
+		UnhealthyThreshold: hc.UnhealthyThreshold,
+		HealthyThreshold:   hc.HealthyThreshold,
+		Port:               hc.Port,
 	}
 
-	origHC := lb.HealthChecks[0]
+	if err := l.client.LoadBalancers.UpdateHealthCheck(ctx, lbID, origHC.ID, request); err != nil {
+		return err
+	}
 
-	if origHC.Type != hc.Type ||
-		origHC.URL != hc.URL ||
-		origHC.Interval != hc.Interval ||
-		origHC.Timeout != hc.Timeout ||
-		origHC.UnhealthyThreshold != hc.UnhealthyThreshold ||
-		origHC.HealthyThreshold != hc.HealthyThreshold ||
-		origHC.Port != hc.Port {
+	l.eventf(service, v1.EventTypeNormal, eventReasonUpdatingHealthCheck, "Updating health check %s", origHC.ID)
 
-		request := &ah.LBHealthCheckUpdateRequest{
-			Type:               hc.Type,
-			URL:                hc.URL,
-			Interval:           hc.Interval,
-			Timeout:            hc.Timeout,
-			UnhealthyThreshold: hc.UnhealthyThreshold,
-			HealthyThreshold:   hc.HealthyThreshold,
-			Port:               hc.Port,
+	hcID := origHC.ID
+	stateFunc := func(ctx context.Context) (state string, err error) {
+		hc, err := l.client.LoadBalancers.GetHealthCheck(ctx, lbID, hcID)
+		if err != nil {
+			return "", err
 		}
+		return hc.State, nil
+	}
 
-		if err = l.client.LoadBalancers.UpdateHealthCheck(ctx, lb.ID, origHC.ID, request); err != nil {
-			return err
-		}
+	l.pending.add(lbID, fmt.Sprintf("health check %s", hcID), stateFunc, "active")
 
-		stateFunc := func(ctx context.Context) (state string, err error) {
-			hc, err := l.client.LoadBalancers.GetHealthCheck(ctx, lb.ID, origHC.ID)
-			if err != nil {
-				return "", err
-			}
-			return hc.State, nil
-		}
+	return nil
+}
 
-		if err := waitForState(ctx, stateFunc, "active"); err != nil {
+func healthCheckValueEqual(current, expected int) bool {
+	return expected == 0 || current == expected
+}
+
+func (l *loadbalancers) deleteHealthChecks(ctx context.Context, service *v1.Service, lb *ah.LoadBalancer) error {
+	for _, hc := range lb.HealthChecks {
+		if err := l.deleteHealthCheck(ctx, service, lb.ID, hc.ID); err != nil {
 			return err
 		}
-
-		return nil
 	}
 	return nil
 }
 
-func (l *loadbalancers) deleteHealthChecks(ctx context.Context, lb *ah.LoadBalancer) error {
-	if len(lb.HealthChecks) == 0 {
-		return nil
-	}
-
-	origHC := lb.HealthChecks[0]
-
-	if err := l.client.LoadBalancers.DeleteHealthCheck(ctx, lb.ID, origHC.ID); err != nil {
+func (l *loadbalancers) deleteHealthCheck(ctx context.Context, service *v1.Service, lbID, hcID string) error {
+	if err := l.client.LoadBalancers.DeleteHealthCheck(ctx, lbID, hcID); err != nil {
 		return err
 	}
 
+	l.eventf(service, v1.EventTypeNormal, eventReasonDeletingHealthCheck, "Deleting health check %s", hcID)
+
 	stateFunc := func(ctx context.Context) (state string, err error) {
-		hc, err := l.client.LoadBalancers.GetHealthCheck(ctx, lb.ID, origHC.ID)
+		hc, err := l.client.LoadBalancers.GetHealthCheck(ctx, lbID, hcID)
 		if err != nil {
 			if err == ah.ErrResourceNotFound {
 				return "deleted", nil
@@ -642,15 +1616,13 @@ func (l *loadbalancers) deleteHealthChecks(ctx context.Context, lb *ah.LoadBalan
 		return hc.State, nil
 	}
 
-	if err := waitForState(ctx, stateFunc, "deleted"); err != nil {
-		return err
-	}
+	l.pending.add(lbID, fmt.Sprintf("health check %s", hcID), stateFunc, "deleted")
 
 	return nil
 
 }
 
-func (l *loadbalancers) updateBackendNodes(ctx context.Context, nodes []*v1.Node, lb *ah.LoadBalancer) error {
+func (l *loadbalancers) updateBackendNodes(ctx context.Context, service *v1.Service, nodes []*v1.Node, lb *ah.LoadBalancer) error {
 	bnsToDelete := make(map[string]ah.LBBackendNode, len(lb.BackendNodes))
 	var bnsToAdd []string
 
@@ -658,13 +1630,12 @@ func (l *loadbalancers) updateBackendNodes(ctx context.Context, nodes []*v1.Node
 		bnsToDelete[bn.CloudServerID] = bn
 	}
 
-	for _, node := range nodes {
-
-		id, err := instanceIDByProviderID(node.Spec.ProviderID)
+	ids, err := l.backendInstanceIDs(ctx, service, nodes)
+	if err != nil {
+		return err
+	}
 
-		if err != nil {
-			return err
-		}
+	for _, id := range ids {
 
 		if _, ok := bnsToDelete[id]; !ok {
 			bnsToAdd = append(bnsToAdd, id)
@@ -678,12 +1649,14 @@ func (l *loadbalancers) updateBackendNodes(ctx context.Context, nodes []*v1.Node
 		if err := l.addBackendNodes(ctx, lb.ID, bnsToAdd); err != nil {
 			return err
 		}
+		l.eventf(service, v1.EventTypeNormal, eventReasonAddingBackendNodes, "Adding backend nodes for instances %s", strings.Join(bnsToAdd, ", "))
 	}
 
 	for _, bn := range bnsToDelete {
 		if err := l.removeBackendNode(ctx, lb.ID, bn.ID); err != nil {
 			return err
 		}
+		l.eventf(service, v1.EventTypeNormal, eventReasonRemovingBackendNode, "Removing backend node of instance %s", bn.CloudServerID)
 	}
 
 	return nil
@@ -715,9 +1688,7 @@ func (l *loadbalancers) addBackendNodes(ctx context.Context, lbID string, bns []
 		return "active", nil
 	}
 
-	if err := waitForState(ctx, stateFunc, "active"); err != nil {
-		return err
-	}
+	l.pending.add(lbID, fmt.Sprintf("backend nodes %s", strings.Join(bns, ", ")), stateFunc, "active")
 
 	return nil
 
@@ -739,9 +1710,7 @@ func (l *loadbalancers) removeBackendNode(ctx context.Context, lbID, bnID string
 		return bn.State, nil
 	}
 
-	if err := waitForState(ctx, stateFunc, "deleted"); err != nil {
-		return err
-	}
+	l.pending.add(lbID, fmt.Sprintf("backend node %s", bnID), stateFunc, "deleted")
 
 	return nil
 
@@ -0,0 +1,141 @@
+/*
+Copyright 2021 Advanced Hosting
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ah
+
+import (
+	"context"
+	"time"
+
+	"github.com/advancedhosting/advancedhosting-api-go/ah"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog"
+)
+
+// loadBalancerGC periodically removes load balancers owned by the cluster
+// that are not referenced by any service of type LoadBalancer.
+type loadBalancerGC struct {
+	loadbalancers *loadbalancers
+	config        gcConfig
+	// orphanedSince keeps the time an orphaned load balancer was first noticed
+	orphanedSince map[string]time.Time
+	now           func() time.Time
+}
+
+func newLoadBalancerGC(loadbalancers *loadbalancers, config gcConfig) *loadBalancerGC {
+	return &loadBalancerGC{
+		loadbalancers: loadbalancers,
+		config:        config,
+		orphanedSince: map[string]time.Time{},
+		now:           time.Now,
+	}
+}
+
+// Run collects orphaned load balancers until stop is closed.
+func (gc *loadBalancerGC) Run(stop <-chan struct{}) {
+	klog.Infof("Starting load balancer garbage collector (dry run: %v)", gc.config.DryRun)
+	wait.Until(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), gc.config.Interval.Duration)
+		defer cancel()
+		if err := gc.collect(ctx); err != nil {
+			klog.Errorf("Error collecting orphaned load balancers: %v", err)
+		}
+	}, gc.config.Interval.Duration, stop)
+}
+
+func (gc *loadBalancerGC) collect(ctx context.Context) error {
+	loadBalancers, err := gc.loadbalancers.client.LoadBalancers.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	services, err := gc.loadbalancers.clusterInfo.kclient.CoreV1().Services(metav1.NamespaceAll).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+
+	referencedIDs := sets.NewString()
+	referencedNames := sets.NewString()
+	for i := range services.Items {
+		service := &services.Items[i]
+		if service.Spec.Type != v1.ServiceTypeLoadBalancer {
+			continue
+		}
+		if lbID := gc.loadbalancers.loadBalancerID(service); lbID != "" {
+			referencedIDs.Insert(lbID)
+		}
+		referencedNames.Insert(gc.loadbalancers.loadBalancerName(service))
+	}
+
+	now := gc.now()
+	orphaned := sets.NewString()
+
+	for i := range loadBalancers {
+		loadBalancer := &loadBalancers[i]
+		if !gc.loadbalancers.ownedByCluster(loadBalancer) ||
+			referencedIDs.Has(loadBalancer.ID) ||
+			referencedNames.Has(loadBalancer.Name) {
+			continue
+		}
+
+		orphaned.Insert(loadBalancer.ID)
+
+		since, ok := gc.orphanedSince[loadBalancer.ID]
+		if !ok {
+			gc.orphanedSince[loadBalancer.ID] = now
+			since = now
+		}
+
+		if now.Sub(since) < gc.config.GracePeriod.Duration || loadBalancer.State == "deleting" {
+			continue
+		}
+
+		if gc.config.DryRun {
+			klog.Warningf("Load balancer %s (%s) is orphaned since %s and would be deleted", loadBalancer.ID, loadBalancer.Name, since.Format(time.RFC3339))
+			continue
+		}
+
+		if err := gc.deleteLoadBalancer(ctx, loadBalancer); err != nil {
+			klog.Errorf("Error deleting orphaned load balancer %s (%s): %v", loadBalancer.ID, loadBalancer.Name, err)
+			orphanedLoadBalancerDeletions.WithLabelValues("error").Inc()
+			continue
+		}
+		klog.Infof("Orphaned load balancer %s (%s) deletion has been started", loadBalancer.ID, loadBalancer.Name)
+		orphanedLoadBalancerDeletions.WithLabelValues("success").Inc()
+	}
+
+	for lbID := range gc.orphanedSince {
+		if !orphaned.Has(lbID) {
+			delete(gc.orphanedSince, lbID)
+		}
+	}
+
+	orphanedLoadBalancers.Set(float64(orphaned.Len()))
+
+	return nil
+}
+
+func (gc *loadBalancerGC) deleteLoadBalancer(ctx context.Context, loadBalancer *ah.LoadBalancer) error {
+	gc.loadbalancers.pending.forget(loadBalancer.ID)
+
+	if err := gc.loadbalancers.client.LoadBalancers.Delete(ctx, loadBalancer.ID); err != nil && err != ah.ErrResourceNotFound {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2021 Advanced Hosting
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ah
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/advancedhosting/advancedhosting-api-go/ah"
+	"github.com/advancedhosting/advancedhosting-cloud-controller-manager/advancedhosting/mocks"
+	"github.com/golang/mock/gomock"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func testGCLoadBalancers() []ah.LoadBalancer {
+	return []ah.LoadBalancer{
+		{ID: "referenced-lb-id", Name: "test-cluster--referenced", State: "active"},
+		{ID: "orphaned-lb-id", Name: "test-cluster--orphaned", State: "active"},
+		{ID: "foreign-lb-id", Name: "other-cluster--orphaned", State: "active"},
+	}
+}
+
+func testGC(mockedLBAPI *mocks.MockLoadBalancersAPI, config gcConfig) *loadBalancerGC {
+	mockedClient := &ah.APIClient{LoadBalancers: mockedLBAPI}
+	clusterInfo := &clusterInfo{ClusterID: "test-cluster", kclient: fake.NewSimpleClientset()}
+
+	svc := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   "default",
+			Name:        "test-service",
+			Annotations: map[string]string{ServiceAnnotationLoadBalancerID: "referenced-lb-id"},
+		},
+		Spec: v1.ServiceSpec{Type: v1.ServiceTypeLoadBalancer},
+	}
+	if _, err := clusterInfo.kclient.CoreV1().Services(svc.Namespace).Create(context.TODO(), svc, metav1.CreateOptions{}); err != nil {
+		panic(err)
+	}
+
+	return newLoadBalancerGC(newLoadbalancers(mockedClient, clusterInfo), config)
+}
+
+func TestLoadBalancerGC_DeleteOrphanedAfterGracePeriod(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	defer ctrl.Finish()
+
+	mockedLBAPI := mocks.NewMockLoadBalancersAPI(ctrl)
+	mockedLBAPI.EXPECT().List(gomock.Any()).Times(2).Return(testGCLoadBalancers(), nil)
+	mockedLBAPI.EXPECT().Delete(gomock.Any(), gomock.Eq("orphaned-lb-id")).Times(1).Return(nil)
+
+	gc := testGC(mockedLBAPI, gcConfig{GracePeriod: metav1.Duration{Duration: time.Hour}})
+
+	now := time.Now()
+	gc.now = func() time.Time { return now }
+
+	if err := gc.collect(context.TODO()); err != nil {
+		t.Errorf("Unexpected Error: %v", err)
+	}
+
+	gc.loadbalancers.pending.add("orphaned-lb-id", "Forwarding rule test-fr-id", nil, "active")
+
+	now = now.Add(2 * time.Hour)
+
+	if err := gc.collect(context.TODO()); err != nil {
+		t.Errorf("Unexpected Error: %v", err)
+	}
+
+	if err := gc.loadbalancers.pending.err("orphaned-lb-id"); err != nil {
+		t.Errorf("Pending operations of the deleted load balancer are kept: %v", err)
+	}
+
+}
+
+func TestLoadBalancerGC_DryRun(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	defer ctrl.Finish()
+
+	mockedLBAPI := mocks.NewMockLoadBalancersAPI(ctrl)
+	mockedLBAPI.EXPECT().List(gomock.Any()).Times(1).Return(testGCLoadBalancers(), nil)
+
+	gc := testGC(mockedLBAPI, gcConfig{DryRun: true})
+
+	if err := gc.collect(context.TODO()); err != nil {
+		t.Errorf("Unexpected Error: %v", err)
+	}
+
+	if _, ok := gc.orphanedSince["orphaned-lb-id"]; !ok {
+		t.Errorf("Orphaned load balancer is not tracked")
+	}
+
+	if len(gc.orphanedSince) != 1 {
+		t.Errorf("Unexpected result, expected 1 orphaned load balancer. got: %v", len(gc.orphanedSince))
+	}
+
+}
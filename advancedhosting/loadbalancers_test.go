@@ -19,8 +19,12 @@ package ah
 import (
 	"context"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/advancedhosting/advancedhosting-api-go/ah"
 	"github.com/advancedhosting/advancedhosting-cloud-controller-manager/advancedhosting/mocks"
@@ -29,6 +33,9 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+	cloudprovider "k8s.io/cloud-provider"
+	"k8s.io/component-base/metrics/testutil"
 )
 
 func testAnnotaions() map[string]string {
@@ -235,6 +242,159 @@ func TestLoadBalancers_EnsureLoadBalancerCreateNewLB(t *testing.T) {
 
 }
 
+func TestLoadBalancers_EnsureLoadBalancerAdoptExistingLB(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	defer ctrl.Finish()
+
+	mockedLBAPI := mocks.NewMockLoadBalancersAPI(ctrl)
+
+	testLB := testLBGetResponse()
+	testLB.ID = "test-existing-lb-id"
+	testLB.Name = "test-cluster-id--test-lb-name"
+	testLB.State = "creating"
+
+	otherLB := testLBGetResponse()
+	otherLB.ID = "test-other-lb-id"
+	otherLB.Name = "test-other-lb-name"
+
+	mockedLBAPI.EXPECT().List(gomock.Any()).Return([]ah.LoadBalancer{*otherLB, *testLB}, nil)
+
+	mockedClient := &ah.APIClient{LoadBalancers: mockedLBAPI}
+	clusterInfo := &clusterInfo{kclient: fake.NewSimpleClientset(), ClusterID: "test-cluster-id"}
+	loadBalancers := newLoadbalancers(mockedClient, clusterInfo)
+
+	anno := testAnnotaions()
+	delete(anno, ServiceAnnotationLoadBalancerID)
+	svc := testService(clusterInfo.kclient, anno, testPorts())
+
+	_, err := loadBalancers.EnsureLoadBalancer(context.TODO(), "test-sluster-name", svc, testNodes())
+
+	if err.Error() != fmt.Sprintf("Load balancer is not active yet: %s", testLB.State) {
+		t.Errorf("Unexpected Error: %v", err)
+	}
+
+	updatedService, err := clusterInfo.kclient.CoreV1().Services(svc.Namespace).Get(context.TODO(), svc.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Error getting service: %v", err)
+	}
+
+	if lbID := updatedService.Annotations[ServiceAnnotationLoadBalancerID]; testLB.ID != lbID {
+		t.Errorf("Unexpected result, expected %v. got: %v", testLB.ID, lbID)
+	}
+
+}
+
+func TestLoadBalancers_AdoptLoadBalancerClaimedByAnotherService(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	defer ctrl.Finish()
+
+	mockedLBAPI := mocks.NewMockLoadBalancersAPI(ctrl)
+
+	testLB := testLBGetResponse()
+	testLB.ID = "test-existing-lb-id"
+	testLB.Name = "test-cluster-id--test-lb-name"
+
+	mockedLBAPI.EXPECT().List(gomock.Any()).Return([]ah.LoadBalancer{*testLB}, nil)
+
+	mockedClient := &ah.APIClient{LoadBalancers: mockedLBAPI}
+	clusterInfo := &clusterInfo{kclient: fake.NewSimpleClientset(), ClusterID: "test-cluster-id"}
+	loadBalancers := newLoadbalancers(mockedClient, clusterInfo)
+
+	// the other service shares the load balancer name and already owns the load balancer
+	other := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default",
+			Name:      "test-other-service",
+			Annotations: map[string]string{
+				ServiceAnnotationLoadBalancerID:   testLB.ID,
+				ServiceAnnotationLoadBalancerName: "test-lb-name",
+			},
+		},
+	}
+	if _, err := clusterInfo.kclient.CoreV1().Services(other.Namespace).Create(context.TODO(), other, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("Error creating service: %v", err)
+	}
+
+	anno := testAnnotaions()
+	delete(anno, ServiceAnnotationLoadBalancerID)
+	svc := testService(clusterInfo.kclient, anno, testPorts())
+
+	_, err := loadBalancers.adoptLoadBalancer(context.TODO(), svc)
+
+	if err != ah.ErrResourceNotFound {
+		t.Errorf("Unexpected Error: %v", err)
+	}
+
+	updatedService, err := clusterInfo.kclient.CoreV1().Services(svc.Namespace).Get(context.TODO(), svc.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Error getting service: %v", err)
+	}
+
+	if lbID, ok := updatedService.Annotations[ServiceAnnotationLoadBalancerID]; ok {
+		t.Errorf("Unexpected load balancer ID: %v", lbID)
+	}
+
+}
+
+func TestLoadBalancers_AdoptLoadBalancerWithoutClusterID(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	defer ctrl.Finish()
+
+	mockedLBAPI := mocks.NewMockLoadBalancersAPI(ctrl)
+
+	mockedClient := &ah.APIClient{LoadBalancers: mockedLBAPI}
+	clusterInfo := &clusterInfo{kclient: fake.NewSimpleClientset()}
+	loadBalancers := newLoadbalancers(mockedClient, clusterInfo)
+
+	anno := testAnnotaions()
+	delete(anno, ServiceAnnotationLoadBalancerID)
+	svc := testService(clusterInfo.kclient, anno, testPorts())
+
+	// a load balancer named test-lb-name may belong to another cluster
+	_, err := loadBalancers.adoptLoadBalancer(context.TODO(), svc)
+
+	if err != ah.ErrResourceNotFound {
+		t.Errorf("Unexpected Error: %v", err)
+	}
+
+}
+
+func TestLoadBalancers_AdoptLoadBalancerDefaultNameWithoutClusterID(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	defer ctrl.Finish()
+
+	mockedLBAPI := mocks.NewMockLoadBalancersAPI(ctrl)
+
+	mockedClient := &ah.APIClient{LoadBalancers: mockedLBAPI}
+	clusterInfo := &clusterInfo{kclient: fake.NewSimpleClientset()}
+	loadBalancers := newLoadbalancers(mockedClient, clusterInfo)
+
+	anno := testAnnotaions()
+	delete(anno, ServiceAnnotationLoadBalancerID)
+	delete(anno, ServiceAnnotationLoadBalancerName)
+	svc := testService(clusterInfo.kclient, anno, testPorts())
+	svc.UID = "test-uid"
+
+	testLB := testLBGetResponse()
+	testLB.Name = cloudprovider.DefaultLoadBalancerName(svc)
+	mockedLBAPI.EXPECT().List(gomock.Any()).Return([]ah.LoadBalancer{*testLB}, nil)
+
+	loadBalancer, err := loadBalancers.adoptLoadBalancer(context.TODO(), svc)
+
+	if err != nil {
+		t.Errorf("Unexpected Error: %v", err)
+	}
+
+	if loadBalancer == nil || loadBalancer.ID != testLB.ID {
+		t.Errorf("Unexpected result, expected %v. got: %v", testLB, loadBalancer)
+	}
+
+}
+
 func TestLoadBalancers_EnsureNotReadyLoadBalancer(t *testing.T) {
 	ctrl := gomock.NewController(t)
 
@@ -293,14 +453,25 @@ func TestLoadBalancers_EnsureActiveLoadBalancer(t *testing.T) {
 
 }
 
+func testExpectPendingOperations(t *testing.T, err error) {
+	t.Helper()
+
+	if err == nil || !strings.Contains(err.Error(), "has pending operations") {
+		t.Errorf("Unexpected Error: %v", err)
+	}
+}
+
 func TestLoadBalancers_UpdateBalancingAlgorithm(t *testing.T) {
 	ctrl := gomock.NewController(t)
 
 	defer ctrl.Finish()
 
 	mockedLBAPI := mocks.NewMockLoadBalancersAPI(ctrl)
-	mockedLBAPI.EXPECT().Get(gomock.Any(), gomock.Any()).Times(2).Return(testLBGetResponse(), nil)
+	updatedLB := testLBGetResponse()
+	updatedLB.BalancingAlgorithm = "least_requests"
+	mockedLBAPI.EXPECT().Get(gomock.Any(), gomock.Any()).Times(1).Return(testLBGetResponse(), nil)
 	mockedLBAPI.EXPECT().Update(gomock.Any(), gomock.Any(), gomock.Eq(&ah.LoadBalancerUpdateRequest{BalancingAlgorithm: "least_requests"})).Return(nil)
+	mockedLBAPI.EXPECT().Get(gomock.Any(), gomock.Any()).Times(2).Return(updatedLB, nil)
 	mockedClient := &ah.APIClient{LoadBalancers: mockedLBAPI}
 
 	clusterInfo := &clusterInfo{kclient: fake.NewSimpleClientset()}
@@ -310,6 +481,10 @@ func TestLoadBalancers_UpdateBalancingAlgorithm(t *testing.T) {
 	anno[ServiceAnnotationLoadBalancerBalancingAlgorithm] = "least_requests"
 	svc := testService(clusterInfo.kclient, anno, testPorts())
 
+	_, err := loadBalancers.EnsureLoadBalancer(context.TODO(), "test-sluster-name", svc, testNodes())
+
+	testExpectPendingOperations(t, err)
+
 	status, err := loadBalancers.EnsureLoadBalancer(context.TODO(), "test-sluster-name", svc, testNodes())
 
 	expectedResult := &v1.LoadBalancerStatus{
@@ -336,8 +511,11 @@ func TestLoadBalancers_UpdateLBName(t *testing.T) {
 	defer ctrl.Finish()
 
 	mockedLBAPI := mocks.NewMockLoadBalancersAPI(ctrl)
-	mockedLBAPI.EXPECT().Get(gomock.Any(), gomock.Any()).Times(2).Return(testLBGetResponse(), nil)
+	updatingLB := testLBGetResponse()
+	updatingLB.State = "updating"
+	mockedLBAPI.EXPECT().Get(gomock.Any(), gomock.Any()).Times(1).Return(testLBGetResponse(), nil)
 	mockedLBAPI.EXPECT().Update(gomock.Any(), gomock.Any(), gomock.Eq(&ah.LoadBalancerUpdateRequest{Name: "test2"})).Return(nil)
+	mockedLBAPI.EXPECT().Get(gomock.Any(), gomock.Any()).Times(1).Return(updatingLB, nil)
 	mockedClient := &ah.APIClient{LoadBalancers: mockedLBAPI}
 
 	clusterInfo := &clusterInfo{kclient: fake.NewSimpleClientset()}
@@ -347,22 +525,14 @@ func TestLoadBalancers_UpdateLBName(t *testing.T) {
 	anno[ServiceAnnotationLoadBalancerName] = "test2"
 	svc := testService(clusterInfo.kclient, anno, testPorts())
 
-	status, err := loadBalancers.EnsureLoadBalancer(context.TODO(), "test-sluster-name", svc, testNodes())
+	_, err := loadBalancers.EnsureLoadBalancer(context.TODO(), "test-sluster-name", svc, testNodes())
 
-	expectedResult := &v1.LoadBalancerStatus{
-		Ingress: []v1.LoadBalancerIngress{
-			{
-				IP: "1.2.3.4",
-			},
-		},
-	}
+	testExpectPendingOperations(t, err)
 
-	if err != nil {
-		t.Errorf("Unexpected Error: %v", err)
-	}
+	_, err = loadBalancers.EnsureLoadBalancer(context.TODO(), "test-sluster-name", svc, testNodes())
 
-	if !reflect.DeepEqual(expectedResult, status) {
-		t.Errorf("Unexpected result, expected %v. got: %v", expectedResult, status)
+	if err.Error() != fmt.Sprintf("Load balancer is not active yet: %s", updatingLB.State) {
+		t.Errorf("Unexpected Error: %v", err)
 	}
 
 }
@@ -373,6 +543,18 @@ func TestLoadBalancers_UpdateHC(t *testing.T) {
 	defer ctrl.Finish()
 
 	mockedLBAPI := mocks.NewMockLoadBalancersAPI(ctrl)
+	updatedLB := testLBGetResponse()
+	updatedLB.HealthChecks = []ah.LBHealthCheck{
+		{
+			Type:               "http",
+			URL:                "/",
+			Interval:           6,
+			Timeout:            3,
+			UnhealthyThreshold: 4,
+			HealthyThreshold:   3,
+			Port:               9090,
+		},
+	}
 	mockedLBAPI.EXPECT().Get(gomock.Any(), gomock.Any()).Times(1).Return(testLBGetResponse(), nil)
 	updateRequest := &ah.LBHealthCheckUpdateRequest{
 		Type:               "http",
@@ -384,6 +566,7 @@ func TestLoadBalancers_UpdateHC(t *testing.T) {
 		Port:               9090,
 	}
 	mockedLBAPI.EXPECT().UpdateHealthCheck(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Eq(updateRequest)).Return(nil)
+	mockedLBAPI.EXPECT().Get(gomock.Any(), gomock.Any()).Times(3).Return(updatedLB, nil)
 	mockedLBAPI.EXPECT().GetHealthCheck(gomock.Any(), gomock.Any(), gomock.Any()).Times(1).Return(&ah.LBHealthCheck{State: "updating"}, nil)
 	mockedLBAPI.EXPECT().GetHealthCheck(gomock.Any(), gomock.Any(), gomock.Any()).Times(1).Return(&ah.LBHealthCheck{State: "active"}, nil)
 	mockedClient := &ah.APIClient{LoadBalancers: mockedLBAPI}
@@ -391,6 +574,9 @@ func TestLoadBalancers_UpdateHC(t *testing.T) {
 	clusterInfo := &clusterInfo{kclient: fake.NewSimpleClientset()}
 	loadBalancers := newLoadbalancers(mockedClient, clusterInfo)
 
+	now := time.Now()
+	loadBalancers.pending.now = func() time.Time { return now }
+
 	anno := testAnnotaions()
 	anno[ServiceAnnotationLoadBalancerHealthCheckType] = "http"
 	anno[ServiceAnnotationLoadBalancerHealthCheckURL] = "/"
@@ -401,6 +587,21 @@ func TestLoadBalancers_UpdateHC(t *testing.T) {
 	anno[ServiceAnnotationLoadBalancerHealthCheckPort] = "9090"
 	svc := testService(clusterInfo.kclient, anno, testPorts())
 
+	_, err := loadBalancers.EnsureLoadBalancer(context.TODO(), "test-sluster-name", svc, testNodes())
+
+	testExpectPendingOperations(t, err)
+
+	_, err = loadBalancers.EnsureLoadBalancer(context.TODO(), "test-sluster-name", svc, testNodes())
+
+	testExpectPendingOperations(t, err)
+
+	// the health check is not refreshed before the next polling interval
+	_, err = loadBalancers.EnsureLoadBalancer(context.TODO(), "test-sluster-name", svc, testNodes())
+
+	testExpectPendingOperations(t, err)
+
+	now = now.Add(defaultPollingMaxInterval)
+
 	status, err := loadBalancers.EnsureLoadBalancer(context.TODO(), "test-sluster-name", svc, testNodes())
 
 	expectedResult := &v1.LoadBalancerStatus{
@@ -427,9 +628,11 @@ func TestLoadBalancers_DisableHC(t *testing.T) {
 	defer ctrl.Finish()
 
 	mockedLBAPI := mocks.NewMockLoadBalancersAPI(ctrl)
+	updatedLB := testLBGetResponse()
+	updatedLB.HealthChecks = nil
 	mockedLBAPI.EXPECT().Get(gomock.Any(), gomock.Any()).Times(1).Return(testLBGetResponse(), nil)
 	mockedLBAPI.EXPECT().DeleteHealthCheck(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
-	mockedLBAPI.EXPECT().GetHealthCheck(gomock.Any(), gomock.Any(), gomock.Any()).Times(1).Return(&ah.LBHealthCheck{State: "deleting"}, nil)
+	mockedLBAPI.EXPECT().Get(gomock.Any(), gomock.Any()).Times(1).Return(updatedLB, nil)
 	mockedLBAPI.EXPECT().GetHealthCheck(gomock.Any(), gomock.Any(), gomock.Any()).Times(1).Return(nil, ah.ErrResourceNotFound)
 	mockedClient := &ah.APIClient{LoadBalancers: mockedLBAPI}
 
@@ -440,6 +643,10 @@ func TestLoadBalancers_DisableHC(t *testing.T) {
 	anno[ServiceAnnotationLoadBalancerEnableHealthCheck] = "false"
 	svc := testService(clusterInfo.kclient, anno, testPorts())
 
+	_, err := loadBalancers.EnsureLoadBalancer(context.TODO(), "test-sluster-name", svc, testNodes())
+
+	testExpectPendingOperations(t, err)
+
 	status, err := loadBalancers.EnsureLoadBalancer(context.TODO(), "test-sluster-name", svc, testNodes())
 
 	expectedResult := &v1.LoadBalancerStatus{
@@ -479,7 +686,7 @@ func TestLoadBalancers_EnableHC(t *testing.T) {
 		Port:               8080,
 	}
 	mockedLBAPI.EXPECT().CreateHealthCheck(gomock.Any(), gomock.Any(), gomock.Eq(createRequest)).Return(&ah.LBHealthCheck{ID: "test-id"}, nil)
-	mockedLBAPI.EXPECT().GetHealthCheck(gomock.Any(), gomock.Any(), gomock.Eq("test-id")).Times(1).Return(&ah.LBHealthCheck{State: "creating"}, nil)
+	mockedLBAPI.EXPECT().Get(gomock.Any(), gomock.Any()).Times(1).Return(testLBGetResponse(), nil)
 	mockedLBAPI.EXPECT().GetHealthCheck(gomock.Any(), gomock.Any(), gomock.Eq("test-id")).Times(1).Return(&ah.LBHealthCheck{State: "active"}, nil)
 	mockedClient := &ah.APIClient{LoadBalancers: mockedLBAPI}
 
@@ -488,6 +695,10 @@ func TestLoadBalancers_EnableHC(t *testing.T) {
 
 	svc := testService(clusterInfo.kclient, testAnnotaions(), testPorts())
 
+	_, err := loadBalancers.EnsureLoadBalancer(context.TODO(), "test-sluster-name", svc, testNodes())
+
+	testExpectPendingOperations(t, err)
+
 	status, err := loadBalancers.EnsureLoadBalancer(context.TODO(), "test-sluster-name", svc, testNodes())
 
 	expectedResult := &v1.LoadBalancerStatus{
@@ -541,11 +752,32 @@ func TestLoadBalancers_UpdateForwardingRules(t *testing.T) {
 		CommunicationPort:     30003,
 	}
 
-	// update fr
+	// update fr: the outdated rule is removed by the first sync
 	mockedLBAPI.EXPECT().DeleteForwardingRule(gomock.Any(), gomock.Any(), gomock.Eq("fr-to-update-id")).Return(nil)
-	mockedLBAPI.EXPECT().GetForwardingRule(gomock.Any(), gomock.Any(), gomock.Eq("fr-to-update-id")).Times(1).Return(&ah.LBForwardingRule{State: "deleting"}, nil)
+
+	// create fr
+	mockedLBAPI.EXPECT().CreateForwardingRule(gomock.Any(), gomock.Any(), gomock.Eq(createRequest)).Return(&ah.LBForwardingRule{ID: "test-new-id"}, nil)
+
+	// delete unused fr
+	mockedLBAPI.EXPECT().DeleteForwardingRule(gomock.Any(), gomock.Any(), gomock.Eq("fr-to-delete-id")).Return(nil)
+
+	// second sync
+	deletedLB := testLBGetResponse()
+	deletedLB.ForwardingRules = []ah.LBForwardingRule{
+		{
+			ID:                    "test-new-id",
+			RequestProtocol:       "tcp",
+			RequestPort:           90,
+			CommunicationProtocol: "tcp",
+			CommunicationPort:     30003,
+		},
+	}
+	mockedLBAPI.EXPECT().Get(gomock.Any(), gomock.Any()).Times(1).Return(deletedLB, nil)
 	mockedLBAPI.EXPECT().GetForwardingRule(gomock.Any(), gomock.Any(), gomock.Eq("fr-to-update-id")).Times(1).Return(nil, ah.ErrResourceNotFound)
+	mockedLBAPI.EXPECT().GetForwardingRule(gomock.Any(), gomock.Any(), gomock.Eq("test-new-id")).Times(1).Return(&ah.LBForwardingRule{State: "active"}, nil)
+	mockedLBAPI.EXPECT().GetForwardingRule(gomock.Any(), gomock.Any(), gomock.Eq("fr-to-delete-id")).Times(1).Return(nil, ah.ErrResourceNotFound)
 
+	// update fr: the rule is created again by the second sync
 	updateRequest := &ah.LBForwardingRuleCreateRequest{
 		RequestProtocol:       "tcp",
 		RequestPort:           80,
@@ -553,18 +785,6 @@ func TestLoadBalancers_UpdateForwardingRules(t *testing.T) {
 		CommunicationPort:     30002,
 	}
 	mockedLBAPI.EXPECT().CreateForwardingRule(gomock.Any(), gomock.Any(), gomock.Eq(updateRequest)).Return(&ah.LBForwardingRule{ID: "test-updated-id"}, nil)
-	mockedLBAPI.EXPECT().GetForwardingRule(gomock.Any(), gomock.Any(), gomock.Eq("test-updated-id")).Times(1).Return(&ah.LBForwardingRule{State: "creating"}, nil)
-	mockedLBAPI.EXPECT().GetForwardingRule(gomock.Any(), gomock.Any(), gomock.Eq("test-updated-id")).Times(1).Return(&ah.LBForwardingRule{State: "active"}, nil)
-
-	// create fr
-	mockedLBAPI.EXPECT().CreateForwardingRule(gomock.Any(), gomock.Any(), gomock.Eq(createRequest)).Return(&ah.LBForwardingRule{ID: "test-new-id"}, nil)
-	mockedLBAPI.EXPECT().GetForwardingRule(gomock.Any(), gomock.Any(), gomock.Eq("test-new-id")).Times(1).Return(&ah.LBForwardingRule{State: "creating"}, nil)
-	mockedLBAPI.EXPECT().GetForwardingRule(gomock.Any(), gomock.Any(), gomock.Eq("test-new-id")).Times(1).Return(&ah.LBForwardingRule{State: "active"}, nil)
-
-	// delete unused fr
-	mockedLBAPI.EXPECT().DeleteForwardingRule(gomock.Any(), gomock.Any(), gomock.Eq("fr-to-delete-id")).Return(nil)
-	mockedLBAPI.EXPECT().GetForwardingRule(gomock.Any(), gomock.Any(), gomock.Eq("fr-to-delete-id")).Times(1).Return(&ah.LBForwardingRule{State: "deleting"}, nil)
-	mockedLBAPI.EXPECT().GetForwardingRule(gomock.Any(), gomock.Any(), gomock.Eq("fr-to-delete-id")).Times(1).Return(nil, ah.ErrResourceNotFound)
 
 	mockedClient := &ah.APIClient{LoadBalancers: mockedLBAPI}
 
@@ -588,22 +808,16 @@ func TestLoadBalancers_UpdateForwardingRules(t *testing.T) {
 
 	svc := testService(clusterInfo.kclient, testAnnotaions(), ports)
 
-	status, err := loadBalancers.EnsureLoadBalancer(context.TODO(), "test-sluster-name", svc, testNodes())
+	_, err := loadBalancers.EnsureLoadBalancer(context.TODO(), "test-sluster-name", svc, testNodes())
 
-	expectedResult := &v1.LoadBalancerStatus{
-		Ingress: []v1.LoadBalancerIngress{
-			{
-				IP: "1.2.3.4",
-			},
-		},
-	}
+	testExpectPendingOperations(t, err)
 
-	if err != nil {
-		t.Errorf("Unexpected Error: %v", err)
-	}
+	_, err = loadBalancers.EnsureLoadBalancer(context.TODO(), "test-sluster-name", svc, testNodes())
 
-	if !reflect.DeepEqual(expectedResult, status) {
-		t.Errorf("Unexpected result, expected %v. got: %v", expectedResult, status)
+	testExpectPendingOperations(t, err)
+
+	if !strings.Contains(err.Error(), "forwarding rule test-updated-id") {
+		t.Errorf("Unexpected Error: %v", err)
 	}
 
 }
@@ -644,15 +858,28 @@ func TestLoadBalancers_UpdateBackendNodes(t *testing.T) {
 		},
 	}
 	mockedLBAPI.EXPECT().AddBackendNodes(gomock.Any(), gomock.Any(), gomock.Eq(addRequest)).Return(addResponse, nil)
-	mockedLBAPI.EXPECT().ListBackendNodes(gomock.Any(), gomock.Any()).Times(1).Return(addResponse, nil)
-
-	mockedLBAPI.EXPECT().ListBackendNodes(gomock.Any(), gomock.Any()).Times(1).Return([]ah.LBBackendNode{{ID: "test-backend-node-id-3", State: "active"}, {ID: "test-backend-node-id-4", State: "creating"}}, nil)
-
-	mockedLBAPI.EXPECT().ListBackendNodes(gomock.Any(), gomock.Any()).Times(1).Return([]ah.LBBackendNode{{ID: "test-backend-node-id-3", State: "active"}, {ID: "test-backend-node-id-4", State: "active"}}, nil)
 
 	// delete unused bn
 	mockedLBAPI.EXPECT().DeleteBackendNode(gomock.Any(), gomock.Any(), gomock.Eq("test-backend-node-id-2")).Return(nil)
-	mockedLBAPI.EXPECT().GetBackendNode(gomock.Any(), gomock.Any(), gomock.Eq("test-backend-node-id-2")).Times(1).Return(&ah.LBBackendNode{State: "deleting"}, nil)
+
+	// second sync
+	updatedLB := testLBGetResponse()
+	updatedLB.BackendNodes = []ah.LBBackendNode{
+		{
+			ID:            "test-backend-node-id-1",
+			CloudServerID: "test-cloud-server-1",
+		},
+		{
+			ID:            "test-backend-node-id-3",
+			CloudServerID: "test-cloud-server-3",
+		},
+		{
+			ID:            "test-backend-node-id-4",
+			CloudServerID: "test-cloud-server-4",
+		},
+	}
+	mockedLBAPI.EXPECT().Get(gomock.Any(), gomock.Any()).Times(1).Return(updatedLB, nil)
+	mockedLBAPI.EXPECT().ListBackendNodes(gomock.Any(), gomock.Any()).Times(1).Return([]ah.LBBackendNode{{ID: "test-backend-node-id-3", State: "active"}, {ID: "test-backend-node-id-4", State: "active"}}, nil)
 	mockedLBAPI.EXPECT().GetBackendNode(gomock.Any(), gomock.Any(), gomock.Eq("test-backend-node-id-2")).Times(1).Return(nil, ah.ErrResourceNotFound)
 
 	mockedClient := &ah.APIClient{LoadBalancers: mockedLBAPI}
@@ -680,6 +907,10 @@ func TestLoadBalancers_UpdateBackendNodes(t *testing.T) {
 		},
 	}
 
+	_, err := loadBalancers.EnsureLoadBalancer(context.TODO(), "test-sluster-name", svc, nodes)
+
+	testExpectPendingOperations(t, err)
+
 	status, err := loadBalancers.EnsureLoadBalancer(context.TODO(), "test-sluster-name", svc, nodes)
 
 	expectedResult := &v1.LoadBalancerStatus{
@@ -775,3 +1006,1124 @@ func TestLoadBalancers_EnsureAlreadyDeletedLB(t *testing.T) {
 	}
 
 }
+
+func TestLoadBalancers_GetLoadBalancerNameWithClusterID(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	defer ctrl.Finish()
+
+	mockedLBAPI := mocks.NewMockLoadBalancersAPI(ctrl)
+
+	mockedClient := &ah.APIClient{LoadBalancers: mockedLBAPI}
+
+	clusterInfo := &clusterInfo{ClusterID: "test-cluster", kclient: fake.NewSimpleClientset()}
+	loadBalancers := newLoadbalancers(mockedClient, clusterInfo)
+
+	name := loadBalancers.GetLoadBalancerName(context.TODO(), "test-sluster-name", testService(clusterInfo.kclient, testAnnotaions(), testPorts()))
+
+	expectedResult := "test-cluster--test-lb-name"
+
+	if !reflect.DeepEqual(expectedResult, name) {
+		t.Errorf("Unexpected result, expected %v. got: %v", expectedResult, name)
+	}
+
+}
+
+func TestLoadBalancers_EnsureNotOwnedLoadBalancer(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	defer ctrl.Finish()
+
+	mockedLBAPI := mocks.NewMockLoadBalancersAPI(ctrl)
+	mockedLBAPI.EXPECT().Get(gomock.Any(), gomock.Any()).Return(testLBGetResponse(), nil)
+
+	mockedClient := &ah.APIClient{LoadBalancers: mockedLBAPI}
+	clusterInfo := &clusterInfo{ClusterID: "test-cluster", kclient: fake.NewSimpleClientset()}
+	loadBalancers := newLoadbalancers(mockedClient, clusterInfo)
+
+	svc := testService(clusterInfo.kclient, testAnnotaions(), testPorts())
+	_, err := loadBalancers.EnsureLoadBalancer(context.TODO(), "test-sluster-name", svc, testNodes())
+
+	if err == nil || err.Error() != "Load balancer test-lb-id is not owned by cluster test-cluster" {
+		t.Errorf("Unexpected Error: %v", err)
+	}
+
+}
+
+func TestLoadBalancers_DeleteNotOwnedLoadBalancer(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	defer ctrl.Finish()
+
+	mockedLBAPI := mocks.NewMockLoadBalancersAPI(ctrl)
+	mockedLBAPI.EXPECT().Get(gomock.Any(), gomock.Any()).Times(1).Return(testLBGetResponse(), nil)
+	mockedClient := &ah.APIClient{LoadBalancers: mockedLBAPI}
+
+	clusterInfo := &clusterInfo{ClusterID: "test-cluster", kclient: fake.NewSimpleClientset()}
+	loadBalancers := newLoadbalancers(mockedClient, clusterInfo)
+
+	svc := testService(clusterInfo.kclient, testAnnotaions(), testPorts())
+
+	err := loadBalancers.EnsureLoadBalancerDeleted(context.TODO(), "test-sluster-name", svc)
+
+	if err == nil || err.Error() != "Load balancer test-lb-id is not owned by cluster test-cluster" {
+		t.Errorf("Unexpected Error: %v", err)
+	}
+}
+
+func TestLoadBalancers_EnsureInternalLoadBalancer(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	defer ctrl.Finish()
+
+	mockedLBAPI := mocks.NewMockLoadBalancersAPI(ctrl)
+
+	testLB := testLBGetResponse()
+	testLB.IPAddresses = nil
+	testLB.PrivateNetworks = []ah.LBPrivateNetwork{
+		{
+			ID: "test-pn-id",
+			Addresses: []ah.LBPrivateNetworkAddress{
+				{
+					Address: "10.0.0.10",
+				},
+			},
+		},
+	}
+	mockedLBAPI.EXPECT().Get(gomock.Any(), gomock.Any()).Return(testLB, nil)
+
+	mockedClient := &ah.APIClient{LoadBalancers: mockedLBAPI}
+	clusterInfo := &clusterInfo{kclient: fake.NewSimpleClientset()}
+	loadBalancers := newLoadbalancers(mockedClient, clusterInfo)
+
+	anno := testAnnotaions()
+	anno[ServiceAnnotationLoadBalancerInternal] = "true"
+	svc := testService(clusterInfo.kclient, anno, testPorts())
+	status, err := loadBalancers.EnsureLoadBalancer(context.TODO(), "test-sluster-name", svc, testNodes())
+
+	expectedResult := &v1.LoadBalancerStatus{
+		Ingress: []v1.LoadBalancerIngress{
+			{
+				IP: "10.0.0.10",
+			},
+		},
+	}
+
+	if err != nil {
+		t.Errorf("Unexpected Error: %v", err)
+	}
+
+	if !reflect.DeepEqual(expectedResult, status) {
+		t.Errorf("Unexpected result, expected %v. got: %v", expectedResult, status)
+	}
+
+}
+
+func TestLoadBalancers_GetPublicLoadBalancerWithoutPublicIP(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	defer ctrl.Finish()
+
+	mockedLBAPI := mocks.NewMockLoadBalancersAPI(ctrl)
+
+	testLB := testLBGetResponse()
+	testLB.IPAddresses = nil
+	testLB.PrivateNetworks = []ah.LBPrivateNetwork{
+		{
+			ID: "test-pn-id",
+			Addresses: []ah.LBPrivateNetworkAddress{
+				{
+					Address: "10.0.0.10",
+				},
+			},
+		},
+	}
+	mockedLBAPI.EXPECT().Get(gomock.Any(), gomock.Any()).Return(testLB, nil)
+
+	mockedClient := &ah.APIClient{LoadBalancers: mockedLBAPI}
+	clusterInfo := &clusterInfo{kclient: fake.NewSimpleClientset()}
+	loadBalancers := newLoadbalancers(mockedClient, clusterInfo)
+
+	svc := testService(clusterInfo.kclient, testAnnotaions(), testPorts())
+	status, exists, err := loadBalancers.GetLoadBalancer(context.TODO(), "test-sluster-name", svc)
+
+	if err != nil {
+		t.Errorf("Unexpected Error: %v", err)
+	}
+
+	if !exists {
+		t.Errorf("Unexpected result, expected the load balancer to exist")
+	}
+
+	// the private IP of a public load balancer is not reported
+	if !reflect.DeepEqual(&v1.LoadBalancerStatus{}, status) {
+		t.Errorf("Unexpected result, expected an empty status. got: %v", status)
+	}
+
+}
+
+func TestLoadBalancers_EnsureLoadBalancerNoPublicIPAddress(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	defer ctrl.Finish()
+
+	testLB := testLBGetResponse()
+	testLB.IPAddresses = nil
+
+	mockedLBAPI := mocks.NewMockLoadBalancersAPI(ctrl)
+	mockedLBAPI.EXPECT().Get(gomock.Any(), gomock.Any()).Times(2).Return(testLB, nil)
+	mockedClient := &ah.APIClient{LoadBalancers: mockedLBAPI}
+
+	recorder := record.NewFakeRecorder(10)
+	clusterInfo := &clusterInfo{kclient: fake.NewSimpleClientset(), recorder: recorder}
+	loadBalancers := newLoadbalancers(mockedClient, clusterInfo)
+
+	anno := testAnnotaions()
+	delete(anno, ServiceAnnotationLoadBalancerBalancingAlgorithm)
+	svc := testService(clusterInfo.kclient, anno, testPorts())
+
+	for i := 0; i < 2; i++ {
+		_, err := loadBalancers.EnsureLoadBalancer(context.TODO(), "test-sluster-name", svc, testNodes())
+
+		if err != nil {
+			t.Errorf("Unexpected Error: %v", err)
+		}
+	}
+
+	testExpectEvents(t, recorder, "Warning NoPublicIPAddress Load balancer test-lb-id has no public IP address, if it was created internal it can not be made public, recreate the service")
+
+}
+
+func TestLoadBalancers_MakeInternalLoadBalancerCreateRequest(t *testing.T) {
+	clusterInfo := &clusterInfo{PrivateNetworkID: "test-pn-id", kclient: fake.NewSimpleClientset()}
+	loadBalancers := newLoadbalancers(&ah.APIClient{}, clusterInfo)
+
+	anno := testAnnotaions()
+	anno[ServiceAnnotationLoadBalancerInternal] = "true"
+	svc := testService(clusterInfo.kclient, anno, testPorts())
+
+	request, err := loadBalancers.makeLoadBalancerCreateRequest(context.TODO(), svc, testNodes())
+
+	if err != nil {
+		t.Errorf("Unexpected Error: %v", err)
+	}
+
+	if request.CreatePublicIPAddress {
+		t.Errorf("Unexpected result, expected no public IP address")
+	}
+
+	if !reflect.DeepEqual([]string{"test-pn-id"}, request.PrivateNetworkIDs) {
+		t.Errorf("Unexpected result, expected %v. got: %v", []string{"test-pn-id"}, request.PrivateNetworkIDs)
+	}
+
+}
+
+func TestLoadBalancers_EnsurePublicLoadBalancerMadeInternal(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	defer ctrl.Finish()
+
+	mockedLBAPI := mocks.NewMockLoadBalancersAPI(ctrl)
+	mockedLBAPI.EXPECT().Get(gomock.Any(), gomock.Any()).Return(testLBGetResponse(), nil)
+
+	mockedClient := &ah.APIClient{LoadBalancers: mockedLBAPI}
+	clusterInfo := &clusterInfo{kclient: fake.NewSimpleClientset()}
+	loadBalancers := newLoadbalancers(mockedClient, clusterInfo)
+
+	anno := testAnnotaions()
+	anno[ServiceAnnotationLoadBalancerInternal] = "true"
+	svc := testService(clusterInfo.kclient, anno, testPorts())
+	_, err := loadBalancers.EnsureLoadBalancer(context.TODO(), "test-sluster-name", svc, testNodes())
+
+	if err == nil || !strings.Contains(err.Error(), "can not be made internal") {
+		t.Errorf("Unexpected Error: %v", err)
+	}
+
+}
+
+func TestLoadBalancers_MakeLoadBalancerCreateRequestWithReservedIP(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	defer ctrl.Finish()
+
+	mockedIPAPI := mocks.NewMockIPAddressesAPI(ctrl)
+	mockedIPAPI.EXPECT().List(gomock.Any(), gomock.Any()).Return([]ah.IPAddress{{ID: "test-ip-id", Address: "5.6.7.8"}}, nil)
+
+	mockedLBAPI := mocks.NewMockLoadBalancersAPI(ctrl)
+	mockedLBAPI.EXPECT().List(gomock.Any()).Return([]ah.LoadBalancer{*testLBGetResponse()}, nil)
+
+	mockedClient := &ah.APIClient{LoadBalancers: mockedLBAPI, IPAddresses: mockedIPAPI}
+	clusterInfo := &clusterInfo{kclient: fake.NewSimpleClientset()}
+	loadBalancers := newLoadbalancers(mockedClient, clusterInfo)
+
+	svc := testService(clusterInfo.kclient, testAnnotaions(), testPorts())
+	svc.Spec.LoadBalancerIP = "5.6.7.8"
+
+	request, err := loadBalancers.makeLoadBalancerCreateRequest(context.TODO(), svc, testNodes())
+
+	if err != nil {
+		t.Fatalf("Unexpected Error: %v", err)
+	}
+
+	if request.CreatePublicIPAddress {
+		t.Errorf("Unexpected result, expected no public IP address")
+	}
+
+	if !reflect.DeepEqual([]string{"test-ip-id"}, request.IPAddressIDs) {
+		t.Errorf("Unexpected result, expected %v. got: %v", []string{"test-ip-id"}, request.IPAddressIDs)
+	}
+
+}
+
+func TestLoadBalancers_ReservedIPInUse(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	defer ctrl.Finish()
+
+	mockedIPAPI := mocks.NewMockIPAddressesAPI(ctrl)
+	mockedIPAPI.EXPECT().List(gomock.Any(), gomock.Any()).Times(2).Return([]ah.IPAddress{{ID: "test-ip-id", Address: "1.2.3.4"}}, nil)
+
+	mockedLBAPI := mocks.NewMockLoadBalancersAPI(ctrl)
+	mockedLBAPI.EXPECT().List(gomock.Any()).Return([]ah.LoadBalancer{*testLBGetResponse()}, nil)
+
+	mockedClient := &ah.APIClient{LoadBalancers: mockedLBAPI, IPAddresses: mockedIPAPI}
+	clusterInfo := &clusterInfo{kclient: fake.NewSimpleClientset()}
+	loadBalancers := newLoadbalancers(mockedClient, clusterInfo)
+
+	_, err := loadBalancers.reservedIPAddress(context.TODO(), "1.2.3.4", "other-lb-id")
+
+	if err == nil || err.Error() != "Reserved IP address 1.2.3.4 is already in use by load balancer test-lb-id" {
+		t.Errorf("Unexpected Error: %v", err)
+	}
+
+	_, err = loadBalancers.reservedIPAddress(context.TODO(), "4.3.2.1", "other-lb-id")
+
+	if err == nil || err.Error() != "Reserved IP address 4.3.2.1 is not found" {
+		t.Errorf("Unexpected Error: %v", err)
+	}
+
+}
+
+func testUpdateReservedIP(t *testing.T, deleteProtection bool, expectedEvents ...string) {
+	t.Helper()
+
+	ctrl := gomock.NewController(t)
+
+	defer ctrl.Finish()
+
+	mockedIPAPI := mocks.NewMockIPAddressesAPI(ctrl)
+	mockedIPAPI.EXPECT().List(gomock.Any(), gomock.Any()).Return([]ah.IPAddress{{ID: "test-reserved-ip-id", Address: "5.6.7.8"}}, nil)
+
+	mockedLBAPI := mocks.NewMockLoadBalancersAPI(ctrl)
+	testLB := testLBGetResponse()
+	testLB.IPAddresses[0].ID = "test-ip-id"
+	mockedLBAPI.EXPECT().Get(gomock.Any(), gomock.Any()).Times(1).Return(testLB, nil)
+	mockedLBAPI.EXPECT().List(gomock.Any()).Return([]ah.LoadBalancer{*testLB}, nil)
+	mockedLBAPI.EXPECT().AssignIPAddresses(gomock.Any(), gomock.Eq("test-lb-id"), gomock.Eq([]string{"test-reserved-ip-id"})).Return(nil, nil)
+
+	// second sync: the reserved ip is assigned, the previous one is released
+	assignedLB := testLBGetResponse()
+	assignedLB.IPAddresses = []ah.LBIPAddress{
+		{ID: "test-ip-id", Address: "1.2.3.4"},
+		{ID: "test-reserved-ip-id", Address: "5.6.7.8"},
+	}
+	mockedLBAPI.EXPECT().Get(gomock.Any(), gomock.Any()).Times(1).Return(assignedLB, nil)
+	mockedLBAPI.EXPECT().GetIPAddress(gomock.Any(), gomock.Any(), gomock.Eq("test-reserved-ip-id")).Return(&ah.LBIPAddress{State: "active"}, nil)
+	mockedIPAPI.EXPECT().Get(gomock.Any(), gomock.Eq("test-ip-id")).Return(&ah.IPAddress{ID: "test-ip-id", DeleteProtection: deleteProtection}, nil)
+	mockedLBAPI.EXPECT().ReleaseIPAddress(gomock.Any(), gomock.Eq("test-lb-id"), gomock.Eq("test-ip-id")).Return(nil)
+
+	// third sync
+	releasedLB := testLBGetResponse()
+	releasedLB.IPAddresses = []ah.LBIPAddress{{ID: "test-reserved-ip-id", Address: "5.6.7.8"}}
+	mockedLBAPI.EXPECT().Get(gomock.Any(), gomock.Any()).Times(1).Return(releasedLB, nil)
+	mockedLBAPI.EXPECT().GetIPAddress(gomock.Any(), gomock.Any(), gomock.Eq("test-ip-id")).Return(nil, ah.ErrResourceNotFound)
+	if !deleteProtection {
+		mockedIPAPI.EXPECT().Delete(gomock.Any(), gomock.Eq("test-ip-id")).Return(nil)
+	}
+
+	mockedClient := &ah.APIClient{LoadBalancers: mockedLBAPI, IPAddresses: mockedIPAPI}
+	recorder := record.NewFakeRecorder(10)
+	clusterInfo := &clusterInfo{kclient: fake.NewSimpleClientset(), recorder: recorder}
+	loadBalancers := newLoadbalancers(mockedClient, clusterInfo)
+
+	anno := testAnnotaions()
+	anno[ServiceAnnotationLoadBalancerIP] = "5.6.7.8"
+	svc := testService(clusterInfo.kclient, anno, testPorts())
+
+	_, err := loadBalancers.EnsureLoadBalancer(context.TODO(), "test-sluster-name", svc, testNodes())
+
+	testExpectPendingOperations(t, err)
+
+	_, err = loadBalancers.EnsureLoadBalancer(context.TODO(), "test-sluster-name", svc, testNodes())
+
+	testExpectPendingOperations(t, err)
+
+	status, err := loadBalancers.EnsureLoadBalancer(context.TODO(), "test-sluster-name", svc, testNodes())
+
+	expectedResult := &v1.LoadBalancerStatus{
+		Ingress: []v1.LoadBalancerIngress{
+			{
+				IP: "5.6.7.8",
+			},
+		},
+	}
+
+	if err != nil {
+		t.Errorf("Unexpected Error: %v", err)
+	}
+
+	if !reflect.DeepEqual(expectedResult, status) {
+		t.Errorf("Unexpected result, expected %v. got: %v", expectedResult, status)
+	}
+
+	testExpectEvents(t, recorder, expectedEvents...)
+
+}
+
+func TestLoadBalancers_UpdateReservedIP(t *testing.T) {
+	// the previous address was allocated with the load balancer, it is deleted once released
+	testUpdateReservedIP(t, false,
+		"Normal AssigningIPAddress Assigning IP address 5.6.7.8",
+		"Normal ReleasingIPAddress Releasing IP address 1.2.3.4, it is deleted from the account once released",
+		"Normal DeletingIPAddress Deleting previous IP address 1.2.3.4 (ID test-ip-id)",
+	)
+}
+
+func TestLoadBalancers_UpdateReservedIPKeepsDeleteProtectedAddress(t *testing.T) {
+	// the previous address is a reserved one, it stays in the account
+	testUpdateReservedIP(t, true,
+		"Normal AssigningIPAddress Assigning IP address 5.6.7.8",
+		"Normal ReleasingIPAddress Releasing IP address 1.2.3.4, it is delete protected and stays in the account",
+	)
+}
+
+func TestLoadBalancers_ReleaseIPAddressAPIError(t *testing.T) {
+	// the mocks hide the panic of LoadBalancers.ReleaseIPAddress on API errors, use the real client
+	ipAddressFound := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet && ipAddressFound {
+			fmt.Fprint(w, `{"ip_address": {"id": "test-ip-id", "state": "active"}}`)
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	client, err := ah.NewAPIClient(&ah.ClientOptions{BaseURL: server.URL, Token: "test-token"})
+	if err != nil {
+		t.Fatalf("Unexpected Error: %v", err)
+	}
+
+	clusterInfo := &clusterInfo{kclient: fake.NewSimpleClientset()}
+	loadBalancers := newLoadbalancers(client, clusterInfo)
+
+	// the address is released already
+	if err := loadBalancers.releaseIPAddress(context.TODO(), "test-lb-id", "test-ip-id", nil); err != nil {
+		t.Errorf("Unexpected Error: %v", err)
+	}
+
+	ipAddressFound = true
+
+	err = loadBalancers.releaseIPAddress(context.TODO(), "test-lb-id", "test-ip-id", nil)
+
+	if err == nil || err.Error() != "Error releasing ip address test-ip-id of load balancer test-lb-id" {
+		t.Errorf("Unexpected Error: %v", err)
+	}
+
+}
+
+func TestLoadBalancers_DeleteRetainIP(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	defer ctrl.Finish()
+
+	mockedLBAPI := mocks.NewMockLoadBalancersAPI(ctrl)
+	testLB := testLBGetResponse()
+	testLB.IPAddresses[0].ID = "test-ip-id"
+	mockedLBAPI.EXPECT().Get(gomock.Any(), gomock.Any()).Times(1).Return(testLB, nil)
+	mockedLBAPI.EXPECT().ReleaseIPAddress(gomock.Any(), gomock.Eq("test-lb-id"), gomock.Eq("test-ip-id")).Return(nil)
+
+	// second sync: the ip address is released
+	releasedLB := testLBGetResponse()
+	releasedLB.IPAddresses = nil
+	mockedLBAPI.EXPECT().Get(gomock.Any(), gomock.Any()).Times(1).Return(releasedLB, nil)
+	mockedLBAPI.EXPECT().GetIPAddress(gomock.Any(), gomock.Any(), gomock.Eq("test-ip-id")).Return(nil, ah.ErrResourceNotFound)
+	mockedLBAPI.EXPECT().Delete(gomock.Any(), gomock.Eq("test-lb-id")).Return(nil)
+
+	mockedClient := &ah.APIClient{LoadBalancers: mockedLBAPI}
+	recorder := record.NewFakeRecorder(10)
+	clusterInfo := &clusterInfo{kclient: fake.NewSimpleClientset(), recorder: recorder}
+	loadBalancers := newLoadbalancers(mockedClient, clusterInfo)
+
+	anno := testAnnotaions()
+	anno[ServiceAnnotationLoadBalancerRetainIP] = "true"
+	svc := testService(clusterInfo.kclient, anno, testPorts())
+
+	err := loadBalancers.EnsureLoadBalancerDeleted(context.TODO(), "test-sluster-name", svc)
+
+	testExpectPendingOperations(t, err)
+
+	updatedService, err := clusterInfo.kclient.CoreV1().Services(svc.Namespace).Get(context.TODO(), svc.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Error getting service: %v", err)
+	}
+
+	if retainedIP := updatedService.Annotations[ServiceAnnotationLoadBalancerRetainedIP]; retainedIP != "1.2.3.4" {
+		t.Errorf("Unexpected result, expected %v. got: %v", "1.2.3.4", retainedIP)
+	}
+
+	err = loadBalancers.EnsureLoadBalancerDeleted(context.TODO(), "test-sluster-name", svc)
+
+	if err.Error() != "LB deletion has been started" {
+		t.Errorf("Unexpected Error: %v", err)
+	}
+
+	testExpectEvents(t, recorder,
+		"Normal RetainingIPAddress Retaining IP address 1.2.3.4 (ID test-ip-id)",
+		"Normal DeletingLoadBalancer Deletion of load balancer test-lb-id has been started",
+	)
+
+}
+
+func TestLoadBalancers_EnsureLoadBalancerSourceRanges(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	defer ctrl.Finish()
+
+	mockedLBAPI := mocks.NewMockLoadBalancersAPI(ctrl)
+
+	mockedClient := &ah.APIClient{LoadBalancers: mockedLBAPI}
+	clusterInfo := &clusterInfo{kclient: fake.NewSimpleClientset()}
+	loadBalancers := newLoadbalancers(mockedClient, clusterInfo)
+
+	svc := testService(clusterInfo.kclient, testAnnotaions(), testPorts())
+	svc.Spec.LoadBalancerSourceRanges = []string{"192.0.2.0/24"}
+
+	_, err := loadBalancers.EnsureLoadBalancer(context.TODO(), "test-sluster-name", svc, testNodes())
+
+	if err == nil || err.Error() != "Load balancer source ranges 192.0.2.0/24 are not supported, AH load balancers can not restrict client addresses" {
+		t.Errorf("Unexpected Error: %v", err)
+	}
+
+}
+
+func TestLoadBalancers_EnsureLoadBalancerTLS(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	defer ctrl.Finish()
+
+	mockedLBAPI := mocks.NewMockLoadBalancersAPI(ctrl)
+
+	mockedClient := &ah.APIClient{LoadBalancers: mockedLBAPI}
+	clusterInfo := &clusterInfo{kclient: fake.NewSimpleClientset()}
+	loadBalancers := newLoadbalancers(mockedClient, clusterInfo)
+
+	anno := testAnnotaions()
+	anno[ServiceAnnotationLoadBalancerTLSPorts] = "443"
+	anno[ServiceAnnotationLoadBalancerTLSSecret] = "test-tls"
+	svc := testService(clusterInfo.kclient, anno, testPorts())
+
+	_, err := loadBalancers.EnsureLoadBalancer(context.TODO(), "test-sluster-name", svc, testNodes())
+
+	if err == nil || !strings.Contains(err.Error(), "can not terminate TLS") {
+		t.Errorf("Unexpected Error: %v", err)
+	}
+
+}
+
+func TestLoadBalancers_LoadBalancerForwardingRulesProtocols(t *testing.T) {
+	clusterInfo := &clusterInfo{kclient: fake.NewSimpleClientset()}
+	loadBalancers := newLoadbalancers(&ah.APIClient{}, clusterInfo)
+
+	appProtocol := "HTTP"
+	tlsAppProtocol := "https"
+	udpAppProtocol := "udp"
+	ports := []v1.ServicePort{
+		{Name: "web", Protocol: "TCP", Port: 80, NodePort: 30080, AppProtocol: &appProtocol},
+		{Name: "metrics", Protocol: "TCP", Port: 9090, NodePort: 30090},
+		{Name: "api", Protocol: "TCP", Port: 8080, NodePort: 30088},
+		{Name: "tls", Protocol: "TCP", Port: 443, NodePort: 30443, AppProtocol: &tlsAppProtocol},
+		{Name: "dns", Protocol: "UDP", Port: 53, NodePort: 30053, AppProtocol: &appProtocol},
+		{Name: "syslog", Protocol: "TCP", Port: 514, NodePort: 30514, AppProtocol: &udpAppProtocol},
+	}
+
+	anno := testAnnotaions()
+	anno[ServiceAnnotationLoadBalancerProtocols] = "metrics=http:tcp, 8080=http"
+	svc := testService(clusterInfo.kclient, anno, ports)
+
+	requests, err := loadBalancers.loadBalancerForwardingRules(svc)
+
+	expectedResult := []ah.LBForwardingRuleCreateRequest{
+		{RequestProtocol: "http", RequestPort: 80, CommunicationProtocol: "http", CommunicationPort: 30080},
+		{RequestProtocol: "http", RequestPort: 9090, CommunicationProtocol: "tcp", CommunicationPort: 30090},
+		{RequestProtocol: "http", RequestPort: 8080, CommunicationProtocol: "http", CommunicationPort: 30088},
+		{RequestProtocol: "tcp", RequestPort: 443, CommunicationProtocol: "tcp", CommunicationPort: 30443},
+		{RequestProtocol: "udp", RequestPort: 53, CommunicationProtocol: "udp", CommunicationPort: 30053},
+		{RequestProtocol: "tcp", RequestPort: 514, CommunicationProtocol: "tcp", CommunicationPort: 30514},
+	}
+
+	if err != nil {
+		t.Errorf("Unexpected Error: %v", err)
+	}
+
+	if !reflect.DeepEqual(expectedResult, requests) {
+		t.Errorf("Unexpected result, expected %v. got: %v", expectedResult, requests)
+	}
+
+}
+
+func TestLoadBalancers_LoadBalancerForwardingRulesInvalidProtocol(t *testing.T) {
+	clusterInfo := &clusterInfo{kclient: fake.NewSimpleClientset()}
+	loadBalancers := newLoadbalancers(&ah.APIClient{}, clusterInfo)
+
+	anno := testAnnotaions()
+	anno[ServiceAnnotationLoadBalancerProtocols] = "80=grpc"
+	svc := testService(clusterInfo.kclient, anno, testPorts())
+
+	_, err := loadBalancers.loadBalancerForwardingRules(svc)
+
+	if err == nil || err.Error() != `Invalid protocols "80=grpc": unsupported protocol "grpc"` {
+		t.Errorf("Unexpected Error: %v", err)
+	}
+
+}
+
+func TestLoadBalancers_LoadBalancerForwardingRulesHTTPS(t *testing.T) {
+	clusterInfo := &clusterInfo{kclient: fake.NewSimpleClientset()}
+	loadBalancers := newLoadbalancers(&ah.APIClient{}, clusterInfo)
+
+	anno := testAnnotaions()
+	anno[ServiceAnnotationLoadBalancerProtocols] = "80=https"
+	svc := testService(clusterInfo.kclient, anno, testPorts())
+
+	_, err := loadBalancers.loadBalancerForwardingRules(svc)
+
+	if err == nil || err.Error() != `Invalid protocols "80=https": https is not supported, AH load balancers can not terminate TLS` {
+		t.Errorf("Unexpected Error: %v", err)
+	}
+
+}
+
+func TestLoadBalancers_UpdateForwardingRuleProtocol(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	defer ctrl.Finish()
+
+	mockedLBAPI := mocks.NewMockLoadBalancersAPI(ctrl)
+	testLB := testLBGetResponse()
+	testLB.ForwardingRules[0].ID = "test-fr-id"
+	mockedLBAPI.EXPECT().Get(gomock.Any(), gomock.Any()).Times(1).Return(testLB, nil)
+	mockedLBAPI.EXPECT().DeleteForwardingRule(gomock.Any(), gomock.Any(), gomock.Eq("test-fr-id")).Return(nil)
+
+	mockedClient := &ah.APIClient{LoadBalancers: mockedLBAPI}
+	clusterInfo := &clusterInfo{kclient: fake.NewSimpleClientset()}
+	loadBalancers := newLoadbalancers(mockedClient, clusterInfo)
+
+	anno := testAnnotaions()
+	anno[ServiceAnnotationLoadBalancerProtocols] = "test-port=http"
+	svc := testService(clusterInfo.kclient, anno, testPorts())
+
+	_, err := loadBalancers.EnsureLoadBalancer(context.TODO(), "test-sluster-name", svc, testNodes())
+
+	testExpectPendingOperations(t, err)
+
+}
+
+func TestLoadBalancers_EnsureLoadBalancerProxyProtocol(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	defer ctrl.Finish()
+
+	mockedLBAPI := mocks.NewMockLoadBalancersAPI(ctrl)
+
+	mockedClient := &ah.APIClient{LoadBalancers: mockedLBAPI}
+	clusterInfo := &clusterInfo{kclient: fake.NewSimpleClientset()}
+	loadBalancers := newLoadbalancers(mockedClient, clusterInfo)
+
+	anno := testAnnotaions()
+	anno[ServiceAnnotationLoadBalancerProxyProtocol] = "80"
+	svc := testService(clusterInfo.kclient, anno, testPorts())
+
+	_, err := loadBalancers.EnsureLoadBalancer(context.TODO(), "test-sluster-name", svc, testNodes())
+
+	if err == nil || !strings.Contains(err.Error(), "can not send the PROXY protocol header") {
+		t.Errorf("Unexpected Error: %v", err)
+	}
+
+}
+
+func TestLoadBalancers_EnsureLoadBalancerClientIPSessionAffinity(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	defer ctrl.Finish()
+
+	mockedLBAPI := mocks.NewMockLoadBalancersAPI(ctrl)
+	mockedLBAPI.EXPECT().Get(gomock.Any(), gomock.Any()).Times(4).Return(testLBGetResponse(), nil)
+	mockedClient := &ah.APIClient{LoadBalancers: mockedLBAPI}
+
+	recorder := record.NewFakeRecorder(10)
+	clusterInfo := &clusterInfo{kclient: fake.NewSimpleClientset(), recorder: recorder}
+	loadBalancers := newLoadbalancers(mockedClient, clusterInfo)
+
+	anno := testAnnotaions()
+	delete(anno, ServiceAnnotationLoadBalancerBalancingAlgorithm)
+	svc := testService(clusterInfo.kclient, anno, testPorts())
+
+	// the warning is recorded once, and again after the affinity is turned off and on
+	for _, affinity := range []v1.ServiceAffinity{v1.ServiceAffinityClientIP, v1.ServiceAffinityClientIP, v1.ServiceAffinityNone, v1.ServiceAffinityClientIP} {
+		svc.Spec.SessionAffinity = affinity
+
+		_, err := loadBalancers.EnsureLoadBalancer(context.TODO(), "test-sluster-name", svc, testNodes())
+
+		if err != nil {
+			t.Errorf("Unexpected Error: %v", err)
+		}
+	}
+
+	testExpectEvents(t, recorder,
+		"Warning SessionAffinityNotEnforced ClientIP session affinity is not enforced by the load balancer",
+		"Warning SessionAffinityNotEnforced ClientIP session affinity is not enforced by the load balancer",
+	)
+
+}
+
+func testExpectEvents(t *testing.T, recorder *record.FakeRecorder, expectedEvents ...string) {
+	t.Helper()
+
+	close(recorder.Events)
+
+	var events []string
+	for event := range recorder.Events {
+		events = append(events, event)
+	}
+
+	if !reflect.DeepEqual(expectedEvents, events) {
+		t.Errorf("Unexpected result, expected %v. got: %v", expectedEvents, events)
+	}
+}
+
+func TestLoadBalancers_EnsureLoadBalancerCreateNewLBEvents(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	defer ctrl.Finish()
+
+	mockedLBAPI := mocks.NewMockLoadBalancersAPI(ctrl)
+	mockedLBAPI.EXPECT().Get(gomock.Any(), gomock.Any()).Return(nil, ah.ErrResourceNotFound)
+
+	testLB := testLBGetResponse()
+	testLB.State = "creating"
+
+	mockedLBAPI.EXPECT().Create(gomock.Any(), gomock.Any()).Return(testLB, nil)
+
+	mockedClient := &ah.APIClient{LoadBalancers: mockedLBAPI}
+	recorder := record.NewFakeRecorder(10)
+	clusterInfo := &clusterInfo{kclient: fake.NewSimpleClientset(), recorder: recorder}
+	loadBalancers := newLoadbalancers(mockedClient, clusterInfo)
+
+	svc := testService(clusterInfo.kclient, testAnnotaions(), testPorts())
+
+	loadBalancers.EnsureLoadBalancer(context.TODO(), "test-sluster-name", svc, testNodes())
+
+	testExpectEvents(t, recorder, "Normal CreatingLoadBalancer Creating load balancer test-lb-name (test-lb-id)")
+
+}
+
+func TestLoadBalancers_UpdateBackendNodesEvents(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	defer ctrl.Finish()
+
+	mockedLBAPI := mocks.NewMockLoadBalancersAPI(ctrl)
+
+	testLB := testLBGetResponse()
+	testLB.BackendNodes = []ah.LBBackendNode{
+		{
+			ID:            "test-backend-node-id-2",
+			CloudServerID: "test-cloud-server-2",
+		},
+	}
+	mockedLBAPI.EXPECT().Get(gomock.Any(), gomock.Any()).Return(testLB, nil)
+	mockedLBAPI.EXPECT().AddBackendNodes(gomock.Any(), gomock.Any(), gomock.Eq([]string{"test-cloud-server-1"})).Return(nil, nil)
+	mockedLBAPI.EXPECT().DeleteBackendNode(gomock.Any(), gomock.Any(), gomock.Eq("test-backend-node-id-2")).Return(nil)
+
+	mockedClient := &ah.APIClient{LoadBalancers: mockedLBAPI}
+	recorder := record.NewFakeRecorder(10)
+	clusterInfo := &clusterInfo{kclient: fake.NewSimpleClientset(), recorder: recorder}
+	loadBalancers := newLoadbalancers(mockedClient, clusterInfo)
+
+	svc := testService(clusterInfo.kclient, testAnnotaions(), testPorts())
+
+	nodes := []*v1.Node{
+		{
+			Spec: v1.NodeSpec{
+				ProviderID: "advancedhosting://test-cloud-server-1",
+			},
+		},
+	}
+
+	err := loadBalancers.UpdateLoadBalancer(context.TODO(), "test-sluster-name", svc, nodes)
+
+	testExpectPendingOperations(t, err)
+
+	testExpectEvents(t, recorder,
+		"Normal AddingBackendNodes Adding backend nodes for instances test-cloud-server-1",
+		"Normal RemovingBackendNode Removing backend node of instance test-cloud-server-2",
+	)
+
+}
+
+func TestLoadBalancers_InvalidAnnotationsEvent(t *testing.T) {
+	recorder := record.NewFakeRecorder(10)
+	clusterInfo := &clusterInfo{kclient: fake.NewSimpleClientset(), recorder: recorder}
+	loadBalancers := newLoadbalancers(&ah.APIClient{}, clusterInfo)
+
+	anno := testAnnotaions()
+	anno[ServiceAnnotationLoadBalancerInternal] = "maybe"
+	svc := testService(clusterInfo.kclient, anno, testPorts())
+
+	loadBalancers.EnsureLoadBalancer(context.TODO(), "test-sluster-name", svc, testNodes())
+
+	testExpectEvents(t, recorder, `Warning InvalidAnnotations Invalid annotations: service.beta.kubernetes.io/ah-loadbalancer-internal: invalid boolean "maybe"`)
+
+}
+
+func TestLoadBalancers_LoadBalancerHealthCheckRequestsPerPort(t *testing.T) {
+	clusterInfo := &clusterInfo{kclient: fake.NewSimpleClientset()}
+	loadBalancers := newLoadbalancers(&ah.APIClient{}, clusterInfo)
+
+	ports := []v1.ServicePort{
+		{Name: "web", Protocol: "TCP", Port: 80, NodePort: 30080},
+		{Name: "api", Protocol: "TCP", Port: 8080, NodePort: 30088},
+		{Name: "dns", Protocol: "UDP", Port: 53, NodePort: 30053},
+	}
+
+	anno := testAnnotaions()
+	anno[serviceAnnotationLoadBalancerHealthCheckPrefix+"web-type"] = "http"
+	anno[serviceAnnotationLoadBalancerHealthCheckPrefix+"web-url"] = "/healthz"
+	anno[serviceAnnotationLoadBalancerHealthCheckPrefix+"8080-port"] = "31000"
+	anno[serviceAnnotationLoadBalancerHealthCheckPrefix+"8080-unhealthy-threshold"] = "2"
+	svc := testService(clusterInfo.kclient, anno, ports)
+
+	requests, err := loadBalancers.loadBalancerHealthCheckRequests(svc)
+
+	expectedResult := []ah.LBHealthCheckCreateRequest{
+		{Type: "http", URL: "/healthz", Interval: 5, Timeout: 2, UnhealthyThreshold: 5, HealthyThreshold: 5, Port: 30080},
+		{Type: "tcp", URL: "", Interval: 5, Timeout: 2, UnhealthyThreshold: 2, HealthyThreshold: 5, Port: 31000},
+	}
+
+	if err != nil {
+		t.Errorf("Unexpected Error: %v", err)
+	}
+
+	if !reflect.DeepEqual(expectedResult, requests) {
+		t.Errorf("Unexpected result, expected %v. got: %v", expectedResult, requests)
+	}
+
+}
+
+func TestLoadBalancers_LoadBalancerHealthChecksEnabledPerPort(t *testing.T) {
+	clusterInfo := &clusterInfo{kclient: fake.NewSimpleClientset()}
+	loadBalancers := newLoadbalancers(&ah.APIClient{}, clusterInfo)
+
+	anno := map[string]string{
+		serviceAnnotationLoadBalancerHealthCheckPrefix + "test-port-type": "http",
+		serviceAnnotationLoadBalancerHealthCheckPrefix + "test-port-url":  "/healthz",
+	}
+	svc := testService(clusterInfo.kclient, anno, testPorts())
+
+	if !loadBalancers.loadBalancerHealthChecksEnabled(svc) {
+		t.Errorf("Unexpected result, expected health checks to be enabled")
+	}
+
+	requests, err := loadBalancers.loadBalancerHealthCheckRequests(svc)
+
+	expectedResult := []ah.LBHealthCheckCreateRequest{
+		{Type: "http", URL: "/healthz", Port: 30000},
+	}
+
+	if err != nil {
+		t.Errorf("Unexpected Error: %v", err)
+	}
+
+	if !reflect.DeepEqual(expectedResult, requests) {
+		t.Errorf("Unexpected result, expected %v. got: %v", expectedResult, requests)
+	}
+
+	anno[ServiceAnnotationLoadBalancerEnableHealthCheck] = "false"
+
+	if loadBalancers.loadBalancerHealthChecksEnabled(svc) {
+		t.Errorf("Unexpected result, expected health checks to be disabled")
+	}
+
+}
+
+func TestLoadBalancers_UpdateHealthChecksPerPort(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	defer ctrl.Finish()
+
+	mockedLBAPI := mocks.NewMockLoadBalancersAPI(ctrl)
+	testLB := testLBGetResponse()
+	testLB.HealthChecks = []ah.LBHealthCheck{
+		{ID: "hc-web", Type: "tcp", Interval: 5, Timeout: 2, UnhealthyThreshold: 5, HealthyThreshold: 5, Port: 30080},
+		{ID: "hc-old", Type: "tcp", Interval: 5, Timeout: 2, UnhealthyThreshold: 5, HealthyThreshold: 5, Port: 8080},
+		{ID: "hc-extra", Type: "tcp", Interval: 5, Timeout: 2, UnhealthyThreshold: 5, HealthyThreshold: 5, Port: 9090},
+	}
+
+	updateRequest := &ah.LBHealthCheckUpdateRequest{
+		Type:               "tcp",
+		Interval:           5,
+		Timeout:            2,
+		UnhealthyThreshold: 5,
+		HealthyThreshold:   5,
+		Port:               30088,
+	}
+	mockedLBAPI.EXPECT().UpdateHealthCheck(gomock.Any(), gomock.Any(), gomock.Eq("hc-old"), gomock.Eq(updateRequest)).Return(nil)
+	mockedLBAPI.EXPECT().DeleteHealthCheck(gomock.Any(), gomock.Any(), gomock.Eq("hc-extra")).Return(nil)
+	mockedClient := &ah.APIClient{LoadBalancers: mockedLBAPI}
+
+	clusterInfo := &clusterInfo{kclient: fake.NewSimpleClientset()}
+	loadBalancers := newLoadbalancers(mockedClient, clusterInfo)
+
+	ports := []v1.ServicePort{
+		{Name: "web", Protocol: "TCP", Port: 80, NodePort: 30080},
+		{Name: "api", Protocol: "TCP", Port: 8080, NodePort: 30088},
+	}
+
+	anno := testAnnotaions()
+	delete(anno, ServiceAnnotationLoadBalancerHealthCheckPort)
+	anno[serviceAnnotationLoadBalancerHealthCheckPrefix+"80-type"] = "tcp"
+	anno[serviceAnnotationLoadBalancerHealthCheckPrefix+"api-type"] = "tcp"
+	svc := testService(clusterInfo.kclient, anno, ports)
+
+	err := loadBalancers.updateHealthChecks(context.TODO(), svc, testLB)
+
+	if err != nil {
+		t.Errorf("Unexpected Error: %v", err)
+	}
+
+	testExpectPendingOperations(t, loadBalancers.pending.err(testLB.ID))
+
+}
+
+func TestLoadBalancers_LoadBalancerHealthCheckRequestsLocalTraffic(t *testing.T) {
+	clusterInfo := &clusterInfo{kclient: fake.NewSimpleClientset()}
+	loadBalancers := newLoadbalancers(&ah.APIClient{}, clusterInfo)
+
+	svc := testService(clusterInfo.kclient, map[string]string{}, testPorts())
+	svc.Spec.Type = v1.ServiceTypeLoadBalancer
+	svc.Spec.ExternalTrafficPolicy = v1.ServiceExternalTrafficPolicyTypeLocal
+	svc.Spec.HealthCheckNodePort = 32000
+
+	if !loadBalancers.loadBalancerHealthChecksEnabled(svc) {
+		t.Errorf("Unexpected result, expected health checks to be enabled")
+	}
+
+	requests, err := loadBalancers.loadBalancerHealthCheckRequests(svc)
+
+	expectedResult := []ah.LBHealthCheckCreateRequest{
+		{Type: "http", URL: "/healthz", Port: 32000},
+	}
+
+	if err != nil {
+		t.Errorf("Unexpected Error: %v", err)
+	}
+
+	if !reflect.DeepEqual(expectedResult, requests) {
+		t.Errorf("Unexpected result, expected %v. got: %v", expectedResult, requests)
+	}
+
+	svc.Annotations[ServiceAnnotationLoadBalancerHealthCheckType] = "tcp"
+	svc.Annotations[ServiceAnnotationLoadBalancerHealthCheckInterval] = "10"
+
+	requests, err = loadBalancers.loadBalancerHealthCheckRequests(svc)
+
+	expectedResult = []ah.LBHealthCheckCreateRequest{
+		{Type: "tcp", Interval: 10, Port: 32000},
+	}
+
+	if err != nil {
+		t.Errorf("Unexpected Error: %v", err)
+	}
+
+	if !reflect.DeepEqual(expectedResult, requests) {
+		t.Errorf("Unexpected result, expected %v. got: %v", expectedResult, requests)
+	}
+
+	svc.Annotations[ServiceAnnotationLoadBalancerEnableHealthCheck] = "false"
+
+	if loadBalancers.loadBalancerHealthChecksEnabled(svc) {
+		t.Errorf("Unexpected result, expected health checks to be disabled")
+	}
+
+	svc.Spec.ExternalTrafficPolicy = v1.ServiceExternalTrafficPolicyTypeCluster
+	delete(svc.Annotations, ServiceAnnotationLoadBalancerEnableHealthCheck)
+
+	if loadBalancers.loadBalancerHealthChecksEnabled(svc) {
+		t.Errorf("Unexpected result, expected health checks to be disabled")
+	}
+
+}
+
+func TestLoadBalancers_LoadBalancerNodes(t *testing.T) {
+	clusterInfo := &clusterInfo{kclient: fake.NewSimpleClientset()}
+	loadBalancers := newLoadbalancers(&ah.APIClient{}, clusterInfo)
+	recorder := record.NewFakeRecorder(10)
+	clusterInfo.recorder = recorder
+
+	ready := []v1.NodeCondition{{Type: v1.NodeReady, Status: v1.ConditionTrue}}
+	notReady := []v1.NodeCondition{{Type: v1.NodeReady, Status: v1.ConditionFalse}}
+	edge := map[string]string{"pool": "edge"}
+
+	nodes := []*v1.Node{
+		{ObjectMeta: metav1.ObjectMeta{Name: "edge-1", Labels: edge}, Status: v1.NodeStatus{Conditions: ready}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "edge-2", Labels: edge}, Status: v1.NodeStatus{Conditions: notReady}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "edge-3", Labels: edge}, Spec: v1.NodeSpec{Unschedulable: true}, Status: v1.NodeStatus{Conditions: ready}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "master", Labels: map[string]string{"node-role.kubernetes.io/master": ""}}, Status: v1.NodeStatus{Conditions: ready}},
+	}
+
+	anno := testAnnotaions()
+	anno[ServiceAnnotationLoadBalancerNodeSelector] = "pool=edge"
+	svc := testService(clusterInfo.kclient, anno, testPorts())
+
+	result, err := loadBalancers.loadBalancerNodes(svc, nodes)
+
+	if err != nil {
+		t.Errorf("Unexpected Error: %v", err)
+	}
+
+	if !reflect.DeepEqual(nodes[:3], result) {
+		t.Errorf("Unexpected result, expected %v. got: %v", nodes[:3], result)
+	}
+
+	anno[ServiceAnnotationLoadBalancerExcludeUnreadyNodes] = "true"
+
+	result, err = loadBalancers.loadBalancerNodes(svc, nodes)
+
+	if err != nil {
+		t.Errorf("Unexpected Error: %v", err)
+	}
+
+	if !reflect.DeepEqual(nodes[:1], result) {
+		t.Errorf("Unexpected result, expected %v. got: %v", nodes[:1], result)
+	}
+
+	anno[ServiceAnnotationLoadBalancerNodeSelector] = "pool=ingress"
+
+	result, err = loadBalancers.loadBalancerNodes(svc, nodes)
+
+	if err != nil {
+		t.Errorf("Unexpected Error: %v", err)
+	}
+
+	if len(result) != 0 {
+		t.Errorf("Unexpected result, expected no nodes. got: %v", result)
+	}
+
+	testExpectEvents(t, recorder, "Warning NoBackendNodes None of the 4 nodes match the backend node selection of the load balancer")
+
+}
+
+func TestLoadBalancers_EnsureLoadBalancerBackendWeight(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	defer ctrl.Finish()
+
+	mockedLBAPI := mocks.NewMockLoadBalancersAPI(ctrl)
+
+	mockedClient := &ah.APIClient{LoadBalancers: mockedLBAPI}
+	clusterInfo := &clusterInfo{kclient: fake.NewSimpleClientset()}
+	loadBalancers := newLoadbalancers(mockedClient, clusterInfo)
+
+	anno := testAnnotaions()
+	anno[ServiceAnnotationLoadBalancerBackendWeight] = "cpu"
+	svc := testService(clusterInfo.kclient, anno, testPorts())
+
+	_, err := loadBalancers.EnsureLoadBalancer(context.TODO(), "test-sluster-name", svc, testNodes())
+
+	if err == nil || !strings.Contains(err.Error(), "have no backend node weights") {
+		t.Errorf("Unexpected Error: %v", err)
+	}
+
+}
+
+func TestLoadBalancers_EnsureLoadBalancerDrainingTimeout(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	defer ctrl.Finish()
+
+	mockedLBAPI := mocks.NewMockLoadBalancersAPI(ctrl)
+
+	mockedClient := &ah.APIClient{LoadBalancers: mockedLBAPI}
+	clusterInfo := &clusterInfo{kclient: fake.NewSimpleClientset()}
+	loadBalancers := newLoadbalancers(mockedClient, clusterInfo)
+
+	anno := testAnnotaions()
+	anno[ServiceAnnotationLoadBalancerDrainingTimeout] = "60"
+	svc := testService(clusterInfo.kclient, anno, testPorts())
+
+	_, err := loadBalancers.EnsureLoadBalancer(context.TODO(), "test-sluster-name", svc, testNodes())
+
+	if err == nil || !strings.Contains(err.Error(), "can not be disabled or drained") {
+		t.Errorf("Unexpected Error: %v", err)
+	}
+
+}
+
+func TestLoadBalancers_UpdateBackendNodesInvalidProviderID(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	defer ctrl.Finish()
+
+	mockedLBAPI := mocks.NewMockLoadBalancersAPI(ctrl)
+	mockedLBAPI.EXPECT().AddBackendNodes(gomock.Any(), gomock.Any(), gomock.Eq([]string{"test-cloud-server-3"})).Return(nil, nil)
+
+	mockedInstancesAPI := mocks.NewMockInstancesAPI(ctrl)
+	mockedInstancesAPI.EXPECT().List(gomock.Any(), gomock.Any()).Return([]ah.Instance{{ID: "test-cloud-server-3"}}, nil, nil)
+	mockedInstancesAPI.EXPECT().List(gomock.Any(), gomock.Any()).Return([]ah.Instance{}, nil, nil)
+	mockedInstancesAPI.EXPECT().List(gomock.Any(), gomock.Any()).Return([]ah.Instance{{ID: "test-cloud-server-7"}, {ID: "test-cloud-server-8"}}, nil, nil)
+
+	mockedClient := &ah.APIClient{LoadBalancers: mockedLBAPI, Instances: mockedInstancesAPI}
+	recorder := record.NewFakeRecorder(10)
+	clusterInfo := &clusterInfo{kclient: fake.NewSimpleClientset(), recorder: recorder}
+	loadBalancers := newLoadbalancers(mockedClient, clusterInfo)
+
+	svc := testService(clusterInfo.kclient, testAnnotaions(), testPorts())
+
+	nodes := append(testNodes(),
+		&v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-3"}},
+		&v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-4"}},
+		&v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-5"}, Spec: v1.NodeSpec{ProviderID: "aws:///node-5"}},
+		&v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-6"}, Spec: v1.NodeSpec{ProviderID: "advancedhosting://"}},
+		&v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-7"}},
+	)
+
+	skipped, err := testutil.GetCounterMetricValue(skippedBackendNodes.WithLabelValues("invalid_provider_id"))
+	if err != nil {
+		t.Errorf("Unexpected Error: %v", err)
+	}
+
+	err = loadBalancers.updateBackendNodes(context.TODO(), svc, nodes, testLBGetResponse())
+
+	if err != nil {
+		t.Errorf("Unexpected Error: %v", err)
+	}
+
+	testExpectEvents(t, recorder,
+		"Warning SkippedBackendNode Node node-4 has no provider ID and no instance with its name, skipping it as backend node",
+		`Warning SkippedBackendNode Node node-5 has an invalid provider ID "aws:///node-5", skipping it as backend node`,
+		`Warning SkippedBackendNode Node node-6 has an invalid provider ID "advancedhosting://", skipping it as backend node`,
+		`Warning SkippedBackendNode Node node-7 has no provider ID and 2 instances are named "node-7", skipping it as backend node`,
+		"Normal AddingBackendNodes Adding backend nodes for instances test-cloud-server-3",
+	)
+
+	result, err := testutil.GetCounterMetricValue(skippedBackendNodes.WithLabelValues("invalid_provider_id"))
+	if err != nil {
+		t.Errorf("Unexpected Error: %v", err)
+	}
+
+	if result != skipped+2 {
+		t.Errorf("Unexpected result, expected %v. got: %v", skipped+2, result)
+	}
+
+}
@@ -0,0 +1,72 @@
+/*
+Copyright 2021 Advanced Hosting
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ah
+
+import (
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+const metricsNamespace = "advancedhosting_ccm"
+
+var (
+	apiTokenReloads = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Namespace:      metricsNamespace,
+			Name:           "api_token_reloads_total",
+			Help:           "Number of API token reloads from the token file, partitioned by result.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"result"},
+	)
+
+	orphanedLoadBalancers = metrics.NewGauge(
+		&metrics.GaugeOpts{
+			Namespace:      metricsNamespace,
+			Name:           "orphaned_load_balancers",
+			Help:           "Number of load balancers owned by the cluster that no service references.",
+			StabilityLevel: metrics.ALPHA,
+		},
+	)
+
+	orphanedLoadBalancerDeletions = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Namespace:      metricsNamespace,
+			Name:           "orphaned_load_balancer_deletions_total",
+			Help:           "Number of orphaned load balancer deletions, partitioned by result.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"result"},
+	)
+
+	skippedBackendNodes = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Namespace:      metricsNamespace,
+			Name:           "skipped_backend_nodes_total",
+			Help:           "Number of nodes skipped as load balancer backends because their instance could not be resolved, partitioned by reason.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"reason"},
+	)
+)
+
+func init() {
+	legacyregistry.MustRegister(apiTokenReloads)
+	legacyregistry.MustRegister(orphanedLoadBalancers)
+	legacyregistry.MustRegister(orphanedLoadBalancerDeletions)
+	legacyregistry.MustRegister(skippedBackendNodes)
+}
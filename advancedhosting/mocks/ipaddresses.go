@@ -0,0 +1,122 @@
+/*
+Copyright 2021 Advanced Hosting
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	ah "github.com/advancedhosting/advancedhosting-api-go/ah"
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockIPAddressesAPI is a mock of IPAddressesAPI interface.
+type MockIPAddressesAPI struct {
+	ctrl     *gomock.Controller
+	recorder *MockIPAddressesAPIMockRecorder
+}
+
+// MockIPAddressesAPIMockRecorder is the mock recorder for MockIPAddressesAPI.
+type MockIPAddressesAPIMockRecorder struct {
+	mock *MockIPAddressesAPI
+}
+
+// NewMockIPAddressesAPI creates a new mock instance.
+func NewMockIPAddressesAPI(ctrl *gomock.Controller) *MockIPAddressesAPI {
+	mock := &MockIPAddressesAPI{ctrl: ctrl}
+	mock.recorder = &MockIPAddressesAPIMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockIPAddressesAPI) EXPECT() *MockIPAddressesAPIMockRecorder {
+	return m.recorder
+}
+
+// Create mocks base method.
+func (m *MockIPAddressesAPI) Create(arg0 context.Context, arg1 *ah.IPAddressCreateRequest) (*ah.IPAddress, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", arg0, arg1)
+	ret0, _ := ret[0].(*ah.IPAddress)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockIPAddressesAPIMockRecorder) Create(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockIPAddressesAPI)(nil).Create), arg0, arg1)
+}
+
+// Delete mocks base method.
+func (m *MockIPAddressesAPI) Delete(arg0 context.Context, arg1 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockIPAddressesAPIMockRecorder) Delete(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockIPAddressesAPI)(nil).Delete), arg0, arg1)
+}
+
+// Get mocks base method.
+func (m *MockIPAddressesAPI) Get(arg0 context.Context, arg1 string) (*ah.IPAddress, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", arg0, arg1)
+	ret0, _ := ret[0].(*ah.IPAddress)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Get indicates an expected call of Get.
+func (mr *MockIPAddressesAPIMockRecorder) Get(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockIPAddressesAPI)(nil).Get), arg0, arg1)
+}
+
+// List mocks base method.
+func (m *MockIPAddressesAPI) List(arg0 context.Context, arg1 *ah.ListOptions) ([]ah.IPAddress, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List", arg0, arg1)
+	ret0, _ := ret[0].([]ah.IPAddress)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List.
+func (mr *MockIPAddressesAPIMockRecorder) List(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockIPAddressesAPI)(nil).List), arg0, arg1)
+}
+
+// Update mocks base method.
+func (m *MockIPAddressesAPI) Update(arg0 context.Context, arg1 string, arg2 *ah.IPAddressUpdateRequest) (*ah.IPAddress, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Update", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*ah.IPAddress)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Update indicates an expected call of Update.
+func (mr *MockIPAddressesAPIMockRecorder) Update(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockIPAddressesAPI)(nil).Update), arg0, arg1, arg2)
+}
@@ -18,45 +18,239 @@ package ah
 
 import (
 	"context"
+	"fmt"
+	"strings"
+	"sync"
 	"time"
-)
 
-const (
-	duration = 2 * time.Second
+	"github.com/advancedhosting/advancedhosting-api-go/ah"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog"
 )
 
 type stateRefreshFunc func(context.Context) (state string, err error)
 
-func waitForState(ctx context.Context, stateFunc stateRefreshFunc, expectedState string) error {
+// pendingOperation is a change submitted to the API that has not reached the expected state yet.
+type pendingOperation struct {
+	resource      string
+	expectedState string
+	stateFunc     stateRefreshFunc
+	lastState     string
+	deadline      time.Time
+	// nextCheck is the earliest time the state is refreshed again
+	nextCheck time.Time
+	interval  time.Duration
+}
+
+// pendingOperationTimeoutError is returned when an operation has not reached
+// the expected state before its deadline.
+type pendingOperationTimeoutError struct {
+	operation *pendingOperation
+}
+
+func (e *pendingOperationTimeoutError) Error() string {
+	return fmt.Sprintf("Timeout waiting for %s to become %s, last state: %q", e.operation.resource, e.operation.expectedState, e.operation.lastState)
+}
+
+// pendingOperationsError is returned while a load balancer has pending operations.
+// The service controller requeues the service and the next sync continues from there.
+type pendingOperationsError struct {
+	lbID       string
+	operations []*pendingOperation
+}
+
+func (e *pendingOperationsError) Error() string {
+	resources := make([]string, len(e.operations))
+	for i, op := range e.operations {
+		resources[i] = op.resource
+		if op.lastState != "" {
+			resources[i] = fmt.Sprintf("%s (%s)", op.resource, op.lastState)
+		}
+	}
+	return fmt.Sprintf("Load balancer %s has pending operations: %s", e.lbID, strings.Join(resources, ", "))
+}
+
+// pendingOperations keeps the operations submitted for every load balancer
+// between syncs, so that reconciliation never blocks waiting for the API.
+// The state of an operation is refreshed with an exponential backoff to
+// keep the number of API requests low when many load balancers reconcile.
+type pendingOperations struct {
+	mu         sync.Mutex
+	operations map[string][]*pendingOperation
+	config     pollingConfig
+	now        func() time.Time
+}
+
+func newPendingOperations(config pollingConfig) *pendingOperations {
+	config.applyDefaults()
+	return &pendingOperations{
+		operations: map[string][]*pendingOperation{},
+		config:     config,
+		now:        time.Now,
+	}
+}
 
-	ticker := time.NewTicker(duration)
+// add records an operation submitted for the load balancer.
+func (p *pendingOperations) add(lbID, resource string, stateFunc stateRefreshFunc, expectedState string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
 
-	errCh := make(chan error)
+	p.operations[lbID] = append(p.operations[lbID], &pendingOperation{
+		resource:      resource,
+		expectedState: expectedState,
+		stateFunc:     stateFunc,
+		deadline:      p.now().Add(p.config.Timeout.Duration),
+		interval:      p.config.InitialInterval.Duration,
+	})
+}
 
-	go func() {
-		defer close(errCh)
+// err returns an error if the load balancer has pending operations.
+func (p *pendingOperations) err(lbID string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
 
-		for {
-			select {
-			case <-ctx.Done():
-				errCh <- ctx.Err()
-				return
-			case <-ticker.C:
-				state, err := stateFunc(ctx)
-				if err != nil {
-					errCh <- err
-					return
-				}
+	if len(p.operations[lbID]) == 0 {
+		return nil
+	}
+	return &pendingOperationsError{lbID: lbID, operations: p.operations[lbID]}
+}
 
-				if state == expectedState {
-					errCh <- nil
-					return
-				}
+// check refreshes the state of the pending operations of the load balancer
+// that are due, forgets the finished ones and returns an error if some are still pending.
+// An operation that is not finished by its deadline is forgotten and a timeout error
+// is returned, so the next sync submits the change again. So is an operation whose
+// resource is not found anymore, e.g. deleted outside of the CCM.
+func (p *pendingOperations) check(ctx context.Context, lbID string) error {
+	p.mu.Lock()
+	operations := append([]*pendingOperation(nil), p.operations[lbID]...)
+	p.mu.Unlock()
 
+	// The lock is not held during the API calls: operations added in the meantime,
+	// e.g. by a concurrent sync of the service, are kept by remove.
+	finished := map[*pendingOperation]bool{}
+	var stateErr error
+	for _, op := range operations {
+		now := p.now()
+		if now.Before(op.nextCheck) {
+			continue
+		}
+
+		state, err := op.stateFunc(ctx)
+		switch {
+		case err == ah.ErrResourceNotFound:
+			klog.Warningf("%s of load balancer %s is not found, the change is submitted again", op.resource, lbID)
+			finished[op] = true
+			continue
+		case err != nil:
+			// a failed refresh is retried with the same backoff and deadline,
+			// the other operations are still refreshed
+			if stateErr == nil {
+				stateErr = err
 			}
+		case state == op.expectedState:
+			finished[op] = true
+			continue
+		default:
+			op.lastState = state
+		}
+
+		if !now.Before(op.deadline) {
+			finished[op] = true
+			p.remove(lbID, finished)
+			return &pendingOperationTimeoutError{operation: op}
+		}
 
+		p.backoff(op, now)
+	}
+
+	p.remove(lbID, finished)
+
+	if stateErr != nil {
+		return stateErr
+	}
+	return p.err(lbID)
+}
+
+// backoff schedules the next refresh of the operation and increases its interval.
+func (p *pendingOperations) backoff(op *pendingOperation, now time.Time) {
+	interval := op.interval
+	if *p.config.Jitter > 0 {
+		interval = wait.Jitter(interval, *p.config.Jitter)
+	}
+	op.nextCheck = now.Add(interval)
+	op.interval = time.Duration(float64(op.interval) * *p.config.Factor)
+	if op.interval > p.config.MaxInterval.Duration {
+		op.interval = p.config.MaxInterval.Duration
+	}
+}
+
+// remove forgets the given operations of the load balancer and keeps the other ones.
+func (p *pendingOperations) remove(lbID string, operations map[*pendingOperation]bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var remaining []*pendingOperation
+	for _, op := range p.operations[lbID] {
+		if !operations[op] {
+			remaining = append(remaining, op)
+		}
+	}
+
+	if len(remaining) == 0 {
+		delete(p.operations, lbID)
+		return
+	}
+	p.operations[lbID] = remaining
+}
+
+// forget drops the pending operations of the load balancer.
+func (p *pendingOperations) forget(lbID string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	delete(p.operations, lbID)
+}
+
+// reportedWarnings remembers the warnings recorded for a service. A warning about
+// a setting that does not change is recorded once, not on every sync of the service.
+type reportedWarnings struct {
+	mu       sync.Mutex
+	warnings map[types.UID]map[string]bool
+}
+
+func newReportedWarnings() *reportedWarnings {
+	return &reportedWarnings{warnings: map[types.UID]map[string]bool{}}
+}
+
+// report returns true if the warning applies to the service and has not been reported yet.
+// A warning that does not apply anymore is forgotten, so it is reported again if it comes back.
+func (r *reportedWarnings) report(uid types.UID, reason string, applies bool) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !applies {
+		delete(r.warnings[uid], reason)
+		if len(r.warnings[uid]) == 0 {
+			delete(r.warnings, uid)
 		}
-	}()
+		return false
+	}
+
+	if r.warnings[uid][reason] {
+		return false
+	}
+	if r.warnings[uid] == nil {
+		r.warnings[uid] = map[string]bool{}
+	}
+	r.warnings[uid][reason] = true
+	return true
+}
+
+// forget drops the warnings reported for the service.
+func (r *reportedWarnings) forget(uid types.UID) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
 
-	return <-errCh
+	delete(r.warnings, uid)
 }
@@ -0,0 +1,247 @@
+/*
+Copyright 2021 Advanced Hosting
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ah
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/advancedhosting/advancedhosting-api-go/ah"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func testPendingOperations(now *time.Time) *pendingOperations {
+	pending := newPendingOperations(pollingConfig{
+		InitialInterval: metav1.Duration{Duration: time.Second},
+		MaxInterval:     metav1.Duration{Duration: 4 * time.Second},
+		Factor:          float64Ptr(2),
+		Jitter:          float64Ptr(0),
+		Timeout:         metav1.Duration{Duration: time.Minute},
+	})
+	pending.now = func() time.Time { return *now }
+	return pending
+}
+
+func TestPendingOperations_Backoff(t *testing.T) {
+	now := time.Now()
+	pending := testPendingOperations(&now)
+
+	calls := 0
+	stateFunc := func(context.Context) (string, error) {
+		calls++
+		return "updating", nil
+	}
+	pending.add("test-lb-id", "health check test-hc-id", stateFunc, "active")
+
+	// checks are due after 0s, 1s, 2s, 4s, 4s
+	var expectedCalls []int
+	for i := 0; i < 12; i++ {
+		pending.check(context.TODO(), "test-lb-id")
+		expectedCalls = append(expectedCalls, calls)
+		now = now.Add(time.Second)
+	}
+
+	expectedResult := []int{1, 2, 2, 3, 3, 3, 3, 4, 4, 4, 4, 5}
+	for i := range expectedResult {
+		if expectedResult[i] != expectedCalls[i] {
+			t.Errorf("Unexpected result, expected %v. got: %v", expectedResult, expectedCalls)
+			break
+		}
+	}
+
+}
+
+func TestPendingOperations_Timeout(t *testing.T) {
+	now := time.Now()
+	pending := testPendingOperations(&now)
+
+	stateFunc := func(context.Context) (string, error) {
+		return "updating", nil
+	}
+	pending.add("test-lb-id", "health check test-hc-id", stateFunc, "active")
+
+	now = now.Add(2 * time.Minute)
+
+	err := pending.check(context.TODO(), "test-lb-id")
+
+	expectedErr := `Timeout waiting for health check test-hc-id to become active, last state: "updating"`
+	if err == nil || err.Error() != expectedErr {
+		t.Errorf("Unexpected Error: %v", err)
+	}
+
+	if err := pending.err("test-lb-id"); err != nil {
+		t.Errorf("Unexpected Error: %v", err)
+	}
+
+}
+
+func TestPendingOperations_Finished(t *testing.T) {
+	now := time.Now()
+	pending := testPendingOperations(&now)
+
+	state := "updating"
+	stateFunc := func(context.Context) (string, error) {
+		return state, nil
+	}
+	pending.add("test-lb-id", "load balancer test-lb-id", stateFunc, "active")
+
+	err := pending.check(context.TODO(), "test-lb-id")
+
+	if err == nil || !strings.Contains(err.Error(), "load balancer test-lb-id (updating)") {
+		t.Errorf("Unexpected Error: %v", err)
+	}
+
+	state = "active"
+	now = now.Add(time.Second)
+
+	if err := pending.check(context.TODO(), "test-lb-id"); err != nil {
+		t.Errorf("Unexpected Error: %v", err)
+	}
+
+}
+
+func TestPendingOperations_NotFound(t *testing.T) {
+	now := time.Now()
+	pending := testPendingOperations(&now)
+
+	notFound := func(context.Context) (string, error) {
+		return "", ah.ErrResourceNotFound
+	}
+	pending.add("test-lb-id", "health check test-hc-id", notFound, "active")
+
+	calls := 0
+	state := "updating"
+	stateFunc := func(context.Context) (string, error) {
+		calls++
+		return state, nil
+	}
+	pending.add("test-lb-id", "forwarding rule test-fr-id", stateFunc, "active")
+
+	err := pending.check(context.TODO(), "test-lb-id")
+
+	if err == nil || err.Error() != "Load balancer test-lb-id has pending operations: forwarding rule test-fr-id (updating)" {
+		t.Errorf("Unexpected Error: %v", err)
+	}
+
+	state = "active"
+	now = now.Add(time.Second)
+
+	if err := pending.check(context.TODO(), "test-lb-id"); err != nil {
+		t.Errorf("Unexpected Error: %v", err)
+	}
+
+	if calls != 2 {
+		t.Errorf("Unexpected result, expected %v. got: %v", 2, calls)
+	}
+
+}
+
+func TestPendingOperations_StateErrorDoesNotBlockOthers(t *testing.T) {
+	now := time.Now()
+	pending := testPendingOperations(&now)
+
+	failing := func(context.Context) (string, error) {
+		return "", errors.New("test error")
+	}
+	pending.add("test-lb-id", "health check test-hc-id", failing, "active")
+
+	calls := 0
+	stateFunc := func(context.Context) (string, error) {
+		calls++
+		return "active", nil
+	}
+	pending.add("test-lb-id", "forwarding rule test-fr-id", stateFunc, "active")
+
+	err := pending.check(context.TODO(), "test-lb-id")
+
+	if err == nil || err.Error() != "test error" {
+		t.Errorf("Unexpected Error: %v", err)
+	}
+
+	if calls != 1 {
+		t.Errorf("Unexpected result, expected %v. got: %v", 1, calls)
+	}
+
+	err = pending.err("test-lb-id")
+
+	if err == nil || err.Error() != "Load balancer test-lb-id has pending operations: health check test-hc-id" {
+		t.Errorf("Unexpected Error: %v", err)
+	}
+
+}
+
+func TestPendingOperations_StateErrorBackoffAndTimeout(t *testing.T) {
+	now := time.Now()
+	pending := testPendingOperations(&now)
+
+	calls := 0
+	stateFunc := func(context.Context) (string, error) {
+		calls++
+		return "", errors.New("test error")
+	}
+	pending.add("test-lb-id", "health check test-hc-id", stateFunc, "active")
+
+	// failed refreshes are retried after 0s, 1s, 2s, 4s, 4s
+	for i := 0; i < 12; i++ {
+		pending.check(context.TODO(), "test-lb-id")
+		now = now.Add(time.Second)
+	}
+
+	if calls != 5 {
+		t.Errorf("Unexpected result, expected %v. got: %v", 5, calls)
+	}
+
+	now = now.Add(time.Minute)
+
+	err := pending.check(context.TODO(), "test-lb-id")
+
+	if _, ok := err.(*pendingOperationTimeoutError); !ok {
+		t.Errorf("Unexpected Error: %v", err)
+	}
+
+	if err := pending.err("test-lb-id"); err != nil {
+		t.Errorf("Unexpected Error: %v", err)
+	}
+
+}
+
+func TestPendingOperations_AddDuringCheck(t *testing.T) {
+	now := time.Now()
+	pending := testPendingOperations(&now)
+
+	updating := func(context.Context) (string, error) {
+		return "updating", nil
+	}
+
+	// a concurrent sync submits another change while the state is refreshed
+	stateFunc := func(context.Context) (string, error) {
+		pending.add("test-lb-id", "forwarding rule test-fr-id", updating, "active")
+		return "active", nil
+	}
+	pending.add("test-lb-id", "health check test-hc-id", stateFunc, "active")
+
+	err := pending.check(context.TODO(), "test-lb-id")
+
+	expectedErr := "Load balancer test-lb-id has pending operations: forwarding rule test-fr-id"
+	if err == nil || err.Error() != expectedErr {
+		t.Errorf("Unexpected Error: %v", err)
+	}
+
+}
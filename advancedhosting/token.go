@@ -0,0 +1,92 @@
+/*
+Copyright 2021 Advanced Hosting
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ah
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/oauth2"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog"
+)
+
+const tokenReloadPeriod = 30 * time.Second
+
+// fileTokenSource is an oauth2.TokenSource backed by a file, typically a
+// mounted secret. The token is re-read periodically and swapped atomically,
+// so requests that already obtained a token are not affected by a rotation.
+type fileTokenSource struct {
+	path  string
+	token atomic.Value
+}
+
+func newFileTokenSource(path string) (*fileTokenSource, error) {
+	token, err := readTokenFile(path)
+	if err != nil {
+		return nil, err
+	}
+	ts := &fileTokenSource{path: path}
+	ts.token.Store(token)
+	return ts, nil
+}
+
+// Token returns the current token.
+func (ts *fileTokenSource) Token() (*oauth2.Token, error) {
+	return &oauth2.Token{AccessToken: ts.current()}, nil
+}
+
+func (ts *fileTokenSource) current() string {
+	return ts.token.Load().(string)
+}
+
+// Run reloads the token until stop is closed.
+func (ts *fileTokenSource) Run(stop <-chan struct{}) {
+	wait.Until(ts.reload, tokenReloadPeriod, stop)
+}
+
+func (ts *fileTokenSource) reload() {
+	token, err := readTokenFile(ts.path)
+	if err != nil {
+		klog.Errorf("error reloading API token, keeping the current one: %v", err)
+		apiTokenReloads.WithLabelValues("error").Inc()
+		return
+	}
+
+	if token == ts.current() {
+		return
+	}
+
+	ts.token.Store(token)
+	klog.Infof("API token has been reloaded from %s", ts.path)
+	apiTokenReloads.WithLabelValues("success").Inc()
+}
+
+func readTokenFile(path string) (string, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("error reading token file: %v", err)
+	}
+	token := strings.TrimSpace(string(data))
+	if token == "" {
+		return "", fmt.Errorf("token file %s is empty", path)
+	}
+	return token, nil
+}
@@ -0,0 +1,146 @@
+/*
+Copyright 2021 Advanced Hosting
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ah
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func testTokenFile(t *testing.T, token string) (string, func()) {
+	dir, err := ioutil.TempDir("", "ah-ccm")
+	if err != nil {
+		t.Fatalf("Error creating temp dir: %v", err)
+	}
+
+	path := filepath.Join(dir, "token")
+	if err := ioutil.WriteFile(path, []byte(token), 0600); err != nil {
+		t.Fatalf("Error writing token file: %v", err)
+	}
+
+	return path, func() { os.RemoveAll(dir) }
+}
+
+func TestFileTokenSource_Token(t *testing.T) {
+	path, cleanup := testTokenFile(t, "test-token\n")
+	defer cleanup()
+
+	ts, err := newFileTokenSource(path)
+	if err != nil {
+		t.Fatalf("Unexpected Error: %v", err)
+	}
+
+	token, err := ts.Token()
+
+	if err != nil {
+		t.Errorf("Unexpected Error: %v", err)
+	}
+
+	if token.AccessToken != "test-token" {
+		t.Errorf("Unexpected result, expected %v. got: %v", "test-token", token.AccessToken)
+	}
+
+}
+
+func TestFileTokenSource_Reload(t *testing.T) {
+	path, cleanup := testTokenFile(t, "test-token")
+	defer cleanup()
+
+	ts, err := newFileTokenSource(path)
+	if err != nil {
+		t.Fatalf("Unexpected Error: %v", err)
+	}
+
+	if err := ioutil.WriteFile(path, []byte("rotated-token"), 0600); err != nil {
+		t.Fatalf("Error writing token file: %v", err)
+	}
+
+	ts.reload()
+
+	if token := ts.current(); token != "rotated-token" {
+		t.Errorf("Unexpected result, expected %v. got: %v", "rotated-token", token)
+	}
+
+}
+
+func TestFileTokenSource_ReloadKeepsTokenOnError(t *testing.T) {
+	path, cleanup := testTokenFile(t, "test-token")
+	defer cleanup()
+
+	ts, err := newFileTokenSource(path)
+	if err != nil {
+		t.Fatalf("Unexpected Error: %v", err)
+	}
+
+	if err := ioutil.WriteFile(path, []byte(""), 0600); err != nil {
+		t.Fatalf("Error writing token file: %v", err)
+	}
+
+	ts.reload()
+
+	if token := ts.current(); token != "test-token" {
+		t.Errorf("Unexpected result, expected %v. got: %v", "test-token", token)
+	}
+
+}
+
+func TestFileTokenSource_ReloadAuthorizesRequests(t *testing.T) {
+	path, cleanup := testTokenFile(t, "test-token")
+	defer cleanup()
+
+	ts, err := newFileTokenSource(path)
+	if err != nil {
+		t.Fatalf("Unexpected Error: %v", err)
+	}
+
+	var authorization string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+	}))
+	defer server.Close()
+
+	client := newHTTPClient(ts, 0)
+
+	testRequest := func(expected string) {
+		t.Helper()
+
+		resp, err := client.Get(server.URL)
+		if err != nil {
+			t.Fatalf("Unexpected Error: %v", err)
+		}
+		resp.Body.Close()
+
+		if authorization != expected {
+			t.Errorf("Unexpected result, expected %v. got: %v", expected, authorization)
+		}
+	}
+
+	testRequest("Bearer test-token")
+
+	if err := ioutil.WriteFile(path, []byte("rotated-token"), 0600); err != nil {
+		t.Fatalf("Error writing token file: %v", err)
+	}
+
+	ts.reload()
+
+	testRequest("Bearer rotated-token")
+
+}
@@ -0,0 +1,273 @@
+/*
+Copyright 2021 Advanced Hosting
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ah
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+const serviceAnnotationLoadBalancerPrefix = "service.beta.kubernetes.io/ah-loadbalancer-"
+
+// balancingAlgorithms are the balancing algorithms of AH load balancers.
+var balancingAlgorithms = map[string]bool{
+	"round_robin":    true,
+	"least_requests": true,
+}
+
+var healthCheckTypes = map[string]bool{
+	"tcp":  true,
+	"http": true,
+}
+
+// annotationValidators check the value of the annotations that are not free-form.
+var annotationValidators = map[string]func(l *loadbalancers, value string) error{
+	ServiceAnnotationLoadBalancerName:                          validateNotEmpty,
+	ServiceAnnotationLoadBalancerBalancingAlgorithm:            validateBalancingAlgorithm,
+	ServiceAnnotationLoadBalancerEnableHealthCheck:             validateBool,
+	ServiceAnnotationLoadBalancerHealthCheckType:               validateHealthCheckType,
+	ServiceAnnotationLoadBalancerHealthCheckURL:                validateHealthCheckURL,
+	ServiceAnnotationLoadBalancerHealthCheckInterval:           validatePositiveInt,
+	ServiceAnnotationLoadBalancerHealthCheckTimeout:            validatePositiveInt,
+	ServiceAnnotationLoadBalancerHealthCheckUnhealthyThreshold: validatePositiveInt,
+	ServiceAnnotationLoadBalancerHealthCheckHealthyThreshold:   validatePositiveInt,
+	ServiceAnnotationLoadBalancerHealthCheckPort:               validatePort,
+	ServiceAnnotationLoadBalancerInternal:                      validateBool,
+	ServiceAnnotationLoadBalancerIP:                            validateIP,
+	ServiceAnnotationLoadBalancerRetainIP:                      validateBool,
+	ServiceAnnotationLoadBalancerProtocols:                     validateProtocols,
+	ServiceAnnotationLoadBalancerNodeSelector:                  validateLabelSelector,
+	ServiceAnnotationLoadBalancerExcludeUnreadyNodes:           validateBool,
+}
+
+// knownAnnotations are the annotations without a value to validate.
+var knownAnnotations = map[string]bool{
+	ServiceAnnotationLoadBalancerID:         true,
+	ServiceAnnotationLoadBalancerRetainedIP: true,
+}
+
+// validateAnnotations checks every ah-loadbalancer-* annotation of the service before the API is called.
+// The returned error lists all invalid annotations, so they can be fixed at once.
+func (l *loadbalancers) validateAnnotations(service *v1.Service) error {
+	var errs []string
+
+	for key, value := range service.Annotations {
+		if !strings.HasPrefix(key, serviceAnnotationLoadBalancerPrefix) {
+			continue
+		}
+
+		if validator, ok := annotationValidators[key]; ok {
+			if err := validator(l, value); err != nil {
+				errs = append(errs, fmt.Sprintf("%s: %v", key, err))
+			}
+			continue
+		}
+
+		if port, annotation, ok := parseHealthCheckPortAnnotation(key); ok {
+			if !hasServicePort(service, port) {
+				errs = append(errs, fmt.Sprintf("%s: unknown service port %q", key, port))
+			} else if err := annotationValidators[annotation](l, value); err != nil {
+				errs = append(errs, fmt.Sprintf("%s: %v", key, err))
+			}
+			continue
+		}
+
+		if !knownAnnotations[key] && !isUnsupportedAnnotation(key) {
+			errs = append(errs, fmt.Sprintf("%s: unknown annotation", key))
+		}
+	}
+
+	errs = append(errs, l.validateAnnotationRelationships(service)...)
+	sort.Strings(errs)
+
+	if len(errs) > 0 {
+		return fmt.Errorf("Invalid annotations: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// validateAnnotationRelationships checks the annotations that depend on each other or on the service ports.
+// Invalid values are reported by their validator and skipped here.
+func (l *loadbalancers) validateAnnotationRelationships(service *v1.Service) []string {
+	var errs []string
+
+	if timeoutNotLessThanInterval(service.Annotations[ServiceAnnotationLoadBalancerHealthCheckInterval], service.Annotations[ServiceAnnotationLoadBalancerHealthCheckTimeout]) {
+		errs = append(errs, fmt.Sprintf("%s: must be less than %s", ServiceAnnotationLoadBalancerHealthCheckTimeout, ServiceAnnotationLoadBalancerHealthCheckInterval))
+	}
+
+	for port, values := range l.healthCheckPortAnnotations(service) {
+		interval, ok := values[ServiceAnnotationLoadBalancerHealthCheckInterval]
+		if !ok {
+			interval = service.Annotations[ServiceAnnotationLoadBalancerHealthCheckInterval]
+		}
+		timeout, ok := values[ServiceAnnotationLoadBalancerHealthCheckTimeout]
+		if !ok {
+			timeout = service.Annotations[ServiceAnnotationLoadBalancerHealthCheckTimeout]
+		}
+		if timeoutNotLessThanInterval(interval, timeout) {
+			errs = append(errs, fmt.Sprintf("%s%s-timeout: must be less than the health check interval", serviceAnnotationLoadBalancerHealthCheckPrefix, port))
+		}
+	}
+
+	if protocols, err := parseProtocols(service.Annotations[ServiceAnnotationLoadBalancerProtocols]); err == nil {
+		for port, protocol := range protocols {
+			if !hasServicePort(service, port) {
+				errs = append(errs, fmt.Sprintf("%s: unknown service port %q", ServiceAnnotationLoadBalancerProtocols, port))
+				continue
+			}
+			for _, servicePort := range service.Spec.Ports {
+				if !matchesServicePort(servicePort, port) {
+					continue
+				}
+				transport := servicePort.Protocol
+				if transport == "" {
+					transport = v1.ProtocolTCP
+				}
+				for _, p := range []string{protocol.request, protocol.communication} {
+					if !fitsPortProtocol(p, servicePort) {
+						errs = append(errs, fmt.Sprintf("%s: protocol %s of port %q does not run over %s", ServiceAnnotationLoadBalancerProtocols, p, port, transport))
+						break
+					}
+				}
+			}
+		}
+	}
+
+	if service.Annotations[ServiceAnnotationLoadBalancerHealthCheckType] == "tcp" && service.Annotations[ServiceAnnotationLoadBalancerHealthCheckURL] != "" {
+		errs = append(errs, fmt.Sprintf("%s: must not be set for tcp health checks", ServiceAnnotationLoadBalancerHealthCheckURL))
+	}
+
+	if l.loadBalancerInternal(service) && l.loadBalancerIP(service) != "" {
+		errs = append(errs, fmt.Sprintf("%s: reserved IP address can not be assigned to an internal load balancer", ServiceAnnotationLoadBalancerInternal))
+	}
+
+	sort.Strings(errs)
+
+	return errs
+}
+
+// timeoutNotLessThanInterval returns true if both values are valid and the timeout is not less than the interval.
+func timeoutNotLessThanInterval(interval, timeout string) bool {
+	i, err := strconv.Atoi(interval)
+	if err != nil || i <= 0 {
+		return false
+	}
+	t, err := strconv.Atoi(timeout)
+	if err != nil || t <= 0 {
+		return false
+	}
+	return t >= i
+}
+
+func hasServicePort(service *v1.Service, port string) bool {
+	for _, servicePort := range service.Spec.Ports {
+		if matchesServicePort(servicePort, port) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesServicePort returns true if port is the number or the name of the service port.
+func matchesServicePort(servicePort v1.ServicePort, port string) bool {
+	return strconv.Itoa(int(servicePort.Port)) == port || (servicePort.Name != "" && servicePort.Name == port)
+}
+
+func isUnsupportedAnnotation(key string) bool {
+	for _, unsupported := range unsupportedAnnotations {
+		if unsupported.annotation == key {
+			return true
+		}
+	}
+	return false
+}
+
+func validateNotEmpty(l *loadbalancers, value string) error {
+	if value == "" {
+		return fmt.Errorf("must not be empty")
+	}
+	return nil
+}
+
+func validateBool(l *loadbalancers, value string) error {
+	if _, err := strconv.ParseBool(value); err != nil {
+		return fmt.Errorf("invalid boolean %q", value)
+	}
+	return nil
+}
+
+func validatePositiveInt(l *loadbalancers, value string) error {
+	v, err := strconv.Atoi(value)
+	if err != nil || v <= 0 {
+		return fmt.Errorf("invalid value %q, must be a positive integer", value)
+	}
+	return nil
+}
+
+func validatePort(l *loadbalancers, value string) error {
+	v, err := strconv.Atoi(value)
+	if err != nil || v < 1 || v > 65535 {
+		return fmt.Errorf("invalid port %q, must be between 1 and 65535", value)
+	}
+	return nil
+}
+
+func validateIP(l *loadbalancers, value string) error {
+	if net.ParseIP(value) == nil {
+		return fmt.Errorf("invalid IP address %q", value)
+	}
+	return nil
+}
+
+func validateBalancingAlgorithm(l *loadbalancers, value string) error {
+	if !balancingAlgorithms[value] {
+		return fmt.Errorf("unsupported balancing algorithm %q", value)
+	}
+	return nil
+}
+
+func validateHealthCheckType(l *loadbalancers, value string) error {
+	if !healthCheckTypes[value] {
+		return fmt.Errorf("unsupported health check type %q", value)
+	}
+	return nil
+}
+
+func validateHealthCheckURL(l *loadbalancers, value string) error {
+	if value != "" && !strings.HasPrefix(value, "/") {
+		return fmt.Errorf("invalid URL %q, must start with /", value)
+	}
+	return nil
+}
+
+func validateLabelSelector(l *loadbalancers, value string) error {
+	if _, err := labels.Parse(value); err != nil {
+		return fmt.Errorf("invalid label selector %q", value)
+	}
+	return nil
+}
+
+func validateProtocols(l *loadbalancers, value string) error {
+	_, err := parseProtocols(value)
+	return err
+}
@@ -0,0 +1,183 @@
+/*
+Copyright 2021 Advanced Hosting
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ah
+
+import (
+	"context"
+	"testing"
+
+	"github.com/advancedhosting/advancedhosting-api-go/ah"
+	"github.com/advancedhosting/advancedhosting-cloud-controller-manager/advancedhosting/mocks"
+	"github.com/golang/mock/gomock"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestValidation_ValidAnnotations(t *testing.T) {
+	clusterInfo := &clusterInfo{kclient: fake.NewSimpleClientset()}
+	loadBalancers := newLoadbalancers(&ah.APIClient{}, clusterInfo)
+
+	anno := testAnnotaions()
+	anno[ServiceAnnotationLoadBalancerRetainIP] = "true"
+	anno[ServiceAnnotationLoadBalancerIP] = "5.6.7.8"
+	anno[ServiceAnnotationLoadBalancerProtocols] = "80=http"
+	svc := testService(clusterInfo.kclient, anno, testPorts())
+
+	if err := loadBalancers.validateAnnotations(svc); err != nil {
+		t.Errorf("Unexpected Error: %v", err)
+	}
+
+}
+
+func TestValidation_InvalidAnnotations(t *testing.T) {
+	clusterInfo := &clusterInfo{kclient: fake.NewSimpleClientset()}
+	loadBalancers := newLoadbalancers(&ah.APIClient{}, clusterInfo)
+
+	anno := testAnnotaions()
+	anno[ServiceAnnotationLoadBalancerBalancingAlgorithm] = "random"
+	anno[ServiceAnnotationLoadBalancerEnableHealthCheck] = "yes"
+	anno[ServiceAnnotationLoadBalancerHealthCheckPort] = "70000"
+	anno["service.beta.kubernetes.io/ah-loadbalancer-healthcheck-intreval"] = "5"
+	svc := testService(clusterInfo.kclient, anno, testPorts())
+
+	err := loadBalancers.validateAnnotations(svc)
+
+	expectedErr := `Invalid annotations: ` +
+		`service.beta.kubernetes.io/ah-loadbalancer-balancing-algorithm: unsupported balancing algorithm "random"; ` +
+		`service.beta.kubernetes.io/ah-loadbalancer-healthcheck-enabled: invalid boolean "yes"; ` +
+		`service.beta.kubernetes.io/ah-loadbalancer-healthcheck-intreval: unknown annotation; ` +
+		`service.beta.kubernetes.io/ah-loadbalancer-healthcheck-port: invalid port "70000", must be between 1 and 65535`
+
+	if err == nil || err.Error() != expectedErr {
+		t.Errorf("Unexpected Error: %v", err)
+	}
+
+}
+
+func TestValidation_HealthCheckTimeoutLessThanInterval(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	defer ctrl.Finish()
+
+	mockedLBAPI := mocks.NewMockLoadBalancersAPI(ctrl)
+
+	mockedClient := &ah.APIClient{LoadBalancers: mockedLBAPI}
+	clusterInfo := &clusterInfo{kclient: fake.NewSimpleClientset()}
+	loadBalancers := newLoadbalancers(mockedClient, clusterInfo)
+
+	anno := testAnnotaions()
+	anno[ServiceAnnotationLoadBalancerHealthCheckTimeout] = "5"
+	svc := testService(clusterInfo.kclient, anno, testPorts())
+
+	_, err := loadBalancers.EnsureLoadBalancer(context.TODO(), "test-sluster-name", svc, testNodes())
+
+	expectedErr := "Invalid annotations: service.beta.kubernetes.io/ah-loadbalancer-healthcheck-timeout: must be less than service.beta.kubernetes.io/ah-loadbalancer-healthcheck-interval"
+
+	if err == nil || err.Error() != expectedErr {
+		t.Errorf("Unexpected Error: %v", err)
+	}
+
+}
+
+func TestValidation_HealthCheckPortAnnotations(t *testing.T) {
+	clusterInfo := &clusterInfo{kclient: fake.NewSimpleClientset()}
+	loadBalancers := newLoadbalancers(&ah.APIClient{}, clusterInfo)
+
+	anno := testAnnotaions()
+	anno[serviceAnnotationLoadBalancerHealthCheckPrefix+"test-port-type"] = "http"
+	anno[serviceAnnotationLoadBalancerHealthCheckPrefix+"test-port-url"] = "healthz"
+	anno[serviceAnnotationLoadBalancerHealthCheckPrefix+"443-type"] = "tcp"
+	anno[serviceAnnotationLoadBalancerHealthCheckPrefix+"80-timeout"] = "5"
+	svc := testService(clusterInfo.kclient, anno, testPorts())
+
+	err := loadBalancers.validateAnnotations(svc)
+
+	expectedErr := "Invalid annotations: " +
+		`service.beta.kubernetes.io/ah-loadbalancer-healthcheck-443-type: unknown service port "443"; ` +
+		`service.beta.kubernetes.io/ah-loadbalancer-healthcheck-80-timeout: must be less than the health check interval; ` +
+		`service.beta.kubernetes.io/ah-loadbalancer-healthcheck-test-port-url: invalid URL "healthz", must start with /`
+
+	if err == nil || err.Error() != expectedErr {
+		t.Errorf("Unexpected Error: %v", err)
+	}
+
+	delete(anno, serviceAnnotationLoadBalancerHealthCheckPrefix+"test-port-url")
+	delete(anno, serviceAnnotationLoadBalancerHealthCheckPrefix+"443-type")
+
+	err = loadBalancers.validateAnnotations(svc)
+
+	expectedErr = "Invalid annotations: service.beta.kubernetes.io/ah-loadbalancer-healthcheck-80-timeout: must be less than the health check interval"
+
+	if err == nil || err.Error() != expectedErr {
+		t.Errorf("Unexpected Error: %v", err)
+	}
+
+}
+
+func TestValidation_ProtocolsUnknownServicePort(t *testing.T) {
+	clusterInfo := &clusterInfo{kclient: fake.NewSimpleClientset()}
+	loadBalancers := newLoadbalancers(&ah.APIClient{}, clusterInfo)
+
+	anno := testAnnotaions()
+	anno[ServiceAnnotationLoadBalancerProtocols] = "8O=http,test-port=tcp"
+	anno[ServiceAnnotationLoadBalancerBalancingAlgorithm] = "random"
+	svc := testService(clusterInfo.kclient, anno, testPorts())
+
+	err := loadBalancers.validateAnnotations(svc)
+
+	expectedErr := "Invalid annotations: " +
+		`service.beta.kubernetes.io/ah-loadbalancer-balancing-algorithm: unsupported balancing algorithm "random"; ` +
+		`service.beta.kubernetes.io/ah-loadbalancer-protocols: unknown service port "8O"`
+
+	if err == nil || err.Error() != expectedErr {
+		t.Errorf("Unexpected Error: %v", err)
+	}
+
+}
+
+func TestValidation_ProtocolsPortProtocolMismatch(t *testing.T) {
+	clusterInfo := &clusterInfo{kclient: fake.NewSimpleClientset()}
+	loadBalancers := newLoadbalancers(&ah.APIClient{}, clusterInfo)
+
+	ports := []v1.ServicePort{
+		{Name: "web", Protocol: "TCP", Port: 80, NodePort: 30080},
+		{Name: "dns", Protocol: "UDP", Port: 53, NodePort: 30053},
+		{Name: "api", Port: 8080, NodePort: 30088},
+	}
+
+	anno := testAnnotaions()
+	anno[ServiceAnnotationLoadBalancerProtocols] = "53=http,web=udp,api=http:udp"
+	svc := testService(clusterInfo.kclient, anno, ports)
+
+	err := loadBalancers.validateAnnotations(svc)
+
+	expectedErr := "Invalid annotations: " +
+		`service.beta.kubernetes.io/ah-loadbalancer-protocols: protocol http of port "53" does not run over UDP; ` +
+		`service.beta.kubernetes.io/ah-loadbalancer-protocols: protocol udp of port "api" does not run over TCP; ` +
+		`service.beta.kubernetes.io/ah-loadbalancer-protocols: protocol udp of port "web" does not run over TCP`
+
+	if err == nil || err.Error() != expectedErr {
+		t.Errorf("Unexpected Error: %v", err)
+	}
+
+	anno[ServiceAnnotationLoadBalancerProtocols] = "53=udp,web=http,api=http:tcp"
+
+	if err := loadBalancers.validateAnnotations(svc); err != nil {
+		t.Errorf("Unexpected Error: %v", err)
+	}
+
+}
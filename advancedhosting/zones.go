@@ -0,0 +1,78 @@
+/*
+Copyright 2021 Advanced Hosting
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ah
+
+import (
+	"context"
+
+	"github.com/advancedhosting/advancedhosting-api-go/ah"
+	"k8s.io/apimachinery/pkg/types"
+	cloudprovider "k8s.io/cloud-provider"
+)
+
+type zones struct {
+	instances   *instances
+	clusterInfo *clusterInfo
+}
+
+func newZones(instances *instances, clusterInfo *clusterInfo) *zones {
+	return &zones{instances: instances, clusterInfo: clusterInfo}
+}
+
+// GetZone returns the Zone containing the current failure zone and locality region that the program is running in
+// In most cases, this method is called from the kubelet querying a local metadata service to acquire its zone.
+// For the case of external cloud providers, use GetZoneByProviderID or GetZoneByNodeName since GetZone
+// can no longer be called from the kubelets.
+func (z *zones) GetZone(ctx context.Context) (cloudprovider.Zone, error) {
+	return z.datacenterZone(z.clusterInfo.DatacenterSlug), nil
+}
+
+// GetZoneByProviderID returns the Zone containing the current zone and locality region of the node specified by providerID
+// This method is particularly used in the context of external cloud providers where node initialization must be done
+// outside the kubelets.
+func (z *zones) GetZoneByProviderID(ctx context.Context, providerID string) (cloudprovider.Zone, error) {
+	instance, err := z.instances.instanceByProviderID(ctx, providerID)
+	if err != nil {
+		return cloudprovider.Zone{}, err
+	}
+	return z.instanceZone(instance), nil
+}
+
+// GetZoneByNodeName returns the Zone containing the current zone and locality region of the node specified by node name
+// This method is particularly used in the context of external cloud providers where node initialization must be done
+// outside the kubelets.
+func (z *zones) GetZoneByNodeName(ctx context.Context, nodeName types.NodeName) (cloudprovider.Zone, error) {
+	instance, err := z.instances.instanceByName(ctx, nodeName)
+	if err != nil {
+		return cloudprovider.Zone{}, err
+	}
+	return z.instanceZone(instance), nil
+}
+
+func (z *zones) instanceZone(instance *ah.Instance) cloudprovider.Zone {
+	if instance.Datacenter == nil || instance.Datacenter.Slug == "" {
+		return z.datacenterZone(z.clusterInfo.DatacenterSlug)
+	}
+	return z.datacenterZone(instance.Datacenter.Slug)
+}
+
+func (z *zones) datacenterZone(datacenterSlug string) cloudprovider.Zone {
+	return cloudprovider.Zone{
+		FailureDomain: datacenterSlug,
+		Region:        datacenterSlug,
+	}
+}
@@ -0,0 +1,106 @@
+/*
+Copyright 2021 Advanced Hosting
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ah
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/advancedhosting/advancedhosting-api-go/ah"
+	"github.com/advancedhosting/advancedhosting-cloud-controller-manager/advancedhosting/mocks"
+	"github.com/golang/mock/gomock"
+	cloudprovider "k8s.io/cloud-provider"
+)
+
+func TestZones_GetZone(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	defer ctrl.Finish()
+
+	mockedInstancesAPI := mocks.NewMockInstancesAPI(ctrl)
+
+	mockedClient := &ah.APIClient{Instances: mockedInstancesAPI}
+	zones := newZones(newInstances(mockedClient), &clusterInfo{DatacenterSlug: "ams1"})
+
+	zone, err := zones.GetZone(context.TODO())
+
+	expectedResult := cloudprovider.Zone{FailureDomain: "ams1", Region: "ams1"}
+
+	if err != nil {
+		t.Errorf("Unexpected Error: %v", err)
+	}
+
+	if !reflect.DeepEqual(expectedResult, zone) {
+		t.Errorf("Unexpected result, expected %v. got: %v", expectedResult, zone)
+	}
+
+}
+
+func TestZones_GetZoneByProviderID(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	defer ctrl.Finish()
+
+	mockedInstancesAPI := mocks.NewMockInstancesAPI(ctrl)
+
+	mockedInstancesAPI.EXPECT().Get(gomock.Any(), gomock.Eq("test-worker-id")).Return(testInstanceGetResponse(), nil)
+
+	mockedClient := &ah.APIClient{Instances: mockedInstancesAPI}
+	zones := newZones(newInstances(mockedClient), &clusterInfo{DatacenterSlug: "test-dc"})
+
+	zone, err := zones.GetZoneByProviderID(context.TODO(), "advancedhosting://test-worker-id")
+
+	expectedResult := cloudprovider.Zone{FailureDomain: "ams1", Region: "ams1"}
+
+	if err != nil {
+		t.Errorf("Unexpected Error: %v", err)
+	}
+
+	if !reflect.DeepEqual(expectedResult, zone) {
+		t.Errorf("Unexpected result, expected %v. got: %v", expectedResult, zone)
+	}
+
+}
+
+func TestZones_GetZoneByNodeName(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	defer ctrl.Finish()
+
+	mockedInstancesAPI := mocks.NewMockInstancesAPI(ctrl)
+
+	instance := testInstanceGetResponse()
+	instance.Datacenter = nil
+	mockedInstancesAPI.EXPECT().List(gomock.Any(), gomock.Any()).Return([]ah.Instance{*instance}, nil, nil)
+
+	mockedClient := &ah.APIClient{Instances: mockedInstancesAPI}
+	zones := newZones(newInstances(mockedClient), &clusterInfo{DatacenterSlug: "ams1"})
+
+	zone, err := zones.GetZoneByNodeName(context.TODO(), "k8s-worker-test")
+
+	expectedResult := cloudprovider.Zone{FailureDomain: "ams1", Region: "ams1"}
+
+	if err != nil {
+		t.Errorf("Unexpected Error: %v", err)
+	}
+
+	if !reflect.DeepEqual(expectedResult, zone) {
+		t.Errorf("Unexpected result, expected %v. got: %v", expectedResult, zone)
+	}
+
+}
@@ -5,6 +5,7 @@ go 1.16
 require (
 	github.com/advancedhosting/advancedhosting-api-go v0.7.0
 	github.com/golang/mock v1.5.0
+	golang.org/x/oauth2 v0.0.0-20210413134643-5e61552d6c78
 	k8s.io/api v0.19.3
 	k8s.io/apimachinery v0.19.3
 	k8s.io/client-go v0.19.3
@@ -13,6 +14,7 @@ require (
 	k8s.io/klog v1.0.0
 	k8s.io/kubernetes v1.19.7
 	k8s.io/utils v0.0.0 // indirect
+	sigs.k8s.io/yaml v1.2.0
 )
 
 replace (